@@ -1,43 +1,280 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
+	"strings"
 
 	"robot-readme/openapi" // Replace with your actual module name if different
 )
 
+// stringList is a flag that may be given several times, collecting every
+// value.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// progress logs diagnostics to stderr, keeping stdout for the rendered
+// output; -quiet discards them. Fatal errors use log and always print.
+var progress = log.New(os.Stderr, "", log.LstdFlags)
+
 func main() {
-	specPath := "swagger.json"
+	dedupe := flag.Bool("dedupe-schemas", false, "name inline object schemas that repeat across the spec and show them by name")
+	quiet := flag.Bool("quiet", false, "print nothing but fatal errors")
+	qa := flag.Bool("qa", false, "emit question/answer pairs as JSON Lines for RAG ingestion")
+	diffPath := flag.String("diff", "", "path or URL of an older spec to compare the input against; prints what changed")
+	toc := flag.Bool("toc", false, "list every endpoint in a table of contents before the endpoint sections of the text output")
+	wrap := flag.Int("wrap", 0, "wrap descriptions in the text output at this many columns (0 disables wrapping)")
+	stats := flag.Bool("stats", false, "append a footer counting endpoints, methods, tags and schemas to the text output")
+	minify := flag.Bool("minify", false, "render one dense line per endpoint instead of the full text output")
+	withBody := flag.Bool("with-body", false, "keep only endpoints that accept a request body")
+	listServers := flag.Bool("list-servers", false, "print every distinct server URL in the spec and exit")
+	validate := flag.Bool("validate", false, "print endpoints missing summaries or descriptions and exit non-zero if any are found")
+	fieldIndex := flag.Bool("field-index", false, "print every field name with the schemas and endpoints that use it and exit")
+	templatePath := flag.String("template", "", "path to a Go text/template file to render the text output with instead of the built-in layout")
+	format := flag.String("format", "text", "output format: text, markdown, json, spec-yaml or csv")
+	var specPaths stringList
+	flag.Var(&specPaths, "in", "path or http(s) URL of the spec to read, a .zip bundle, or - for stdin; repeat to merge several specs (default swagger.json)")
+	bundleRoot := flag.String("root", "", "path of the root spec inside a .zip bundle (default openapi.yaml, swagger.json or similar)")
+	out := flag.String("out", "", "path to write the output to, or - for stdout (default llm1.txt, llm1.md or llm1.jsonl by format)")
+	methods := flag.String("method", "", "keep only endpoints with these comma-separated methods, e.g. GET,HEAD")
+	lenient := flag.Bool("lenient", false, "warn about unresolved $refs and leave them in place instead of failing")
+	omitReadOnly := flag.Bool("omit-readonly", false, "leave readOnly properties out of request bodies in the text output")
+	skipDeprecated := flag.Bool("skip-deprecated", false, "leave out deprecated endpoints")
+	var pathPrefixes stringList
+	flag.Var(&pathPrefixes, "path-prefix", "keep only endpoints whose path starts with this prefix (repeatable)")
+	flag.Parse()
+	if *quiet {
+		progress.SetOutput(io.Discard)
+	}
 
-	log.Printf("Reading spec from: %s\n", specPath)
-	doc, err := openapi.LoadAPISpec(specPath)
-	if err != nil {
-		log.Fatalf("Error loading API spec: %v", err)
+	outputFile := "llm1.txt"
+	switch *format {
+	case "text":
+	case "markdown":
+		outputFile = "llm1.md"
+	case "json":
+		outputFile = "llm1.json"
+	case "spec-yaml":
+		outputFile = "llm1.yaml"
+	case "csv":
+		outputFile = "llm1.csv"
+	default:
+		log.Fatalf("Unknown -format %q: use text, markdown, json, spec-yaml or csv", *format)
+	}
+	if *qa {
+		outputFile = "llm1.jsonl"
+	}
+	if *out != "" {
+		outputFile = *out
 	}
 
-	// Quick debug: print some top-level info from doc
-	log.Printf("Loaded doc: Title=%s, Version=%s, #Endpoints=%d",
-		doc.Title,
-		doc.Version,
-		len(doc.Endpoints),
-	)
+	if len(specPaths) == 0 {
+		specPaths = stringList{"swagger.json"}
+	}
+	docs := make([]*openapi.APIDocument, 0, len(specPaths))
+	loadOpts := openapi.LoadOptions{BundleRoot: *bundleRoot}
+	for _, path := range specPaths {
+		docs = append(docs, loadSpec(path, loadOpts, *lenient))
+	}
+	doc := docs[0]
+	if len(docs) > 1 {
+		merged, err := openapi.MergeDocuments(docs...)
+		if err != nil {
+			log.Fatalf("Error merging specs: %v", err)
+		}
+		doc = merged
+	}
+	openapi.Normalize(doc)
+	if *dedupe {
+		for _, name := range openapi.DeduplicateSchemas(doc) {
+			progress.Printf("Named repeated inline schema %s", name)
+		}
+	}
+	var oldDoc *openapi.APIDocument
+	if *diffPath != "" {
+		oldDoc = loadSpec(*diffPath, loadOpts, *lenient)
+		openapi.Normalize(oldDoc)
+	}
 
-	if err := openapi.ResolveReferences(doc); err != nil {
-		log.Fatalf("Error resolving references: %v", err)
+	if *listServers {
+		for _, url := range openapi.CollectServers(doc) {
+			fmt.Println(url)
+		}
+		return
+	}
+
+	if *fieldIndex {
+		index := openapi.CollectFieldNames(doc)
+		names := make([]string, 0, len(index))
+		for name := range index {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s: %s\n", name, strings.Join(index[name], ", "))
+		}
+		return
 	}
 
-	summary := openapi.RenderText(doc)
+	// filter applies the endpoint filters, to the old spec of a -diff too.
+	filter := func(doc *openapi.APIDocument) *openapi.APIDocument {
+		if *withBody {
+			doc = openapi.FilterWithRequestBody(doc)
+		}
+		if *skipDeprecated {
+			doc = openapi.FilterWithoutDeprecated(doc)
+		}
+		if len(pathPrefixes) > 0 {
+			doc = openapi.FilterEndpoints(doc, pathPrefixes)
+		}
+		if *methods != "" {
+			doc = openapi.FilterByMethod(doc, strings.Split(*methods, ","))
+		}
+		return doc
+	}
+	doc = filter(doc)
+
+	if *validate {
+		issues := openapi.Validate(doc)
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		if len(issues) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// render writes the output; the text format is streamed endpoint by
+	// endpoint instead of being built in memory first.
+	var render func(w io.Writer) error
+	var summary string
+	var err error
+	switch {
+	case oldDoc != nil:
+		summary = openapi.RenderDiff(openapi.Diff(filter(oldDoc), doc))
+	case *qa:
+		summary, err = openapi.RenderQAJSONL(doc)
+		if err != nil {
+			log.Fatalf("Error rendering Q&A pairs: %v", err)
+		}
+	case *minify:
+		summary = openapi.RenderCompact(doc)
+	case *templatePath != "":
+		tmpl, err := os.ReadFile(*templatePath)
+		if err != nil {
+			log.Fatalf("Error reading template: %v", err)
+		}
+		summary, err = openapi.RenderWithTemplate(doc, string(tmpl))
+		if err != nil {
+			log.Fatalf("Error rendering template: %v", err)
+		}
+	case *format == "markdown":
+		summary = openapi.RenderMarkdown(doc)
+	case *format == "json":
+		summary, err = openapi.RenderJSON(doc)
+		if err != nil {
+			log.Fatalf("Error rendering JSON: %v", err)
+		}
+	case *format == "spec-yaml":
+		summary, err = openapi.RenderSpecYAML(doc)
+		if err != nil {
+			log.Fatalf("Error rendering spec YAML: %v", err)
+		}
+	case *format == "csv":
+		summary, err = openapi.RenderCSV(doc)
+		if err != nil {
+			log.Fatalf("Error rendering CSV: %v", err)
+		}
+	default:
+		render = func(w io.Writer) error {
+			return openapi.RenderTextToWithOptions(w, doc, openapi.RenderOptions{IncludeStats: *stats, IncludeTOC: *toc, WrapWidth: *wrap, OmitReadOnlyInRequests: *omitReadOnly})
+		}
+	}
+	if render == nil {
+		render = func(w io.Writer) error {
+			_, err := io.WriteString(w, summary)
+			return err
+		}
+	}
+
+	if outputFile == "-" {
+		if err := render(os.Stdout); err != nil {
+			log.Fatalf("Error writing to stdout: %v", err)
+		}
+		return
+	}
 
 	// Write to file
-	outputFile := "llm1.txt"
-	log.Printf("Writing summary to %s...", outputFile)
+	progress.Printf("Writing summary to %s...", outputFile)
 
-	err = os.WriteFile(outputFile, []byte(summary), 0644)
+	f, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("Error writing to file: %v", err)
+	}
+	err = render(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		log.Fatalf("Error writing to file: %v", err)
 	}
 
-	fmt.Printf("Successfully wrote API summary to %s\n", outputFile)
+	progress.Printf("Successfully wrote API summary to %s", outputFile)
+}
+
+// loadSpec loads the spec at path, or from stdin for "-", and resolves its
+// references, exiting on failure. Each spec is resolved on its own before
+// merging so that refs into other files are relative to its own directory.
+func loadSpec(path string, opts openapi.LoadOptions, lenient bool) *openapi.APIDocument {
+	stdin := path == "-"
+	remote := strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+	if _, err := os.Stat(path); err != nil && !remote && !stdin {
+		log.Fatalf("Cannot read spec %s: %v (use -in to choose the spec file)", path, err)
+	}
+
+	var doc *openapi.APIDocument
+	var err error
+	if stdin {
+		progress.Printf("Reading spec from stdin")
+		doc, err = openapi.LoadAPISpecReaderWithOptions(os.Stdin, opts)
+	} else {
+		progress.Printf("Reading spec from: %s", path)
+		doc, err = openapi.LoadAPISpecWithOptions(path, opts)
+	}
+	if err != nil {
+		log.Fatalf("Error loading API spec: %v", err)
+	}
+
+	// Quick debug: print some top-level info from doc
+	progress.Printf("Loaded doc: Title=%s, Version=%s, #Endpoints=%d",
+		doc.Title,
+		doc.Version,
+		len(doc.Endpoints),
+	)
+
+	if lenient {
+		unresolved, err := openapi.ResolveReferencesLenient(doc, openapi.ResolveOptions{})
+		if err != nil {
+			log.Fatalf("Error resolving references: %v", err)
+		}
+		for _, msg := range unresolved {
+			progress.Printf("Warning: %s", msg)
+		}
+	} else if err := openapi.ResolveReferences(doc); err != nil {
+		log.Fatalf("Error resolving references: %v", err)
+	}
+	return doc
 }
@@ -0,0 +1,280 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// =====================================================
+// Document Analysis
+// =====================================================
+
+// Relationship records that one resource is nested under another in the URL
+// structure, e.g. /users/{userId}/posts nests posts under users.
+type Relationship struct {
+	Parent string `json:"parent" yaml:"parent"`
+	Child  string `json:"child" yaml:"child"`
+	// Via is the path parameter that identifies the parent, e.g. "userId".
+	Via string `json:"via" yaml:"via"`
+}
+
+// InferRelationships derives parent/child resource relationships from the
+// endpoint paths of doc. A resource segment followed by a {placeholder} and
+// another resource segment is taken to mean the second resource belongs to
+// the first. The result is deduplicated and sorted by parent, then child.
+func InferRelationships(doc *APIDocument) []Relationship {
+	seen := make(map[Relationship]bool)
+	var relationships []Relationship
+	for _, ep := range doc.Endpoints {
+		segments := strings.Split(strings.Trim(ep.Path, "/"), "/")
+		for i := 0; i+2 < len(segments); i++ {
+			parent, id, child := segments[i], segments[i+1], segments[i+2]
+			if isPlaceholder(parent) || !isPlaceholder(id) || isPlaceholder(child) || child == "" {
+				continue
+			}
+			rel := Relationship{Parent: parent, Child: child, Via: strings.Trim(id, "{}")}
+			if !seen[rel] {
+				seen[rel] = true
+				relationships = append(relationships, rel)
+			}
+		}
+	}
+	sort.Slice(relationships, func(i, j int) bool {
+		if relationships[i].Parent != relationships[j].Parent {
+			return relationships[i].Parent < relationships[j].Parent
+		}
+		if relationships[i].Child != relationships[j].Child {
+			return relationships[i].Child < relationships[j].Child
+		}
+		return relationships[i].Via < relationships[j].Via
+	})
+	return relationships
+}
+
+// isPlaceholder reports whether a path segment is a {placeholder}.
+func isPlaceholder(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// CollectServers returns the distinct server URLs that doc refers to, from
+// the document-level servers and any endpoint-level overrides, sorted.
+func CollectServers(doc *APIDocument) []string {
+	seen := make(map[string]bool)
+	var urls []string
+	add := func(servers []Server) {
+		for _, server := range servers {
+			if server.URL != "" && !seen[server.URL] {
+				seen[server.URL] = true
+				urls = append(urls, server.URL)
+			}
+		}
+	}
+	add(doc.Servers)
+	for _, ep := range doc.Endpoints {
+		add(ep.Servers)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// CollectFieldNames indexes the field names used across doc: every schema
+// property name and parameter name is mapped to the sorted owners that use
+// it. A field's owner is the component schema that declares it or, for
+// parameters and inline schemas, the endpoint, e.g. "GET /users/{userId}".
+// The index makes inconsistent spellings such as userId and user_id easy to
+// spot.
+func CollectFieldNames(doc *APIDocument) map[string][]string {
+	owners := make(map[string]map[string]bool)
+	add := func(field, owner string) {
+		if owners[field] == nil {
+			owners[field] = make(map[string]bool)
+		}
+		owners[field][owner] = true
+	}
+
+	visited := make(map[*Schema]bool)
+	var walk func(s *Schema, owner string)
+	walk = func(s *Schema, owner string) {
+		if s == nil || visited[s] {
+			return
+		}
+		visited[s] = true
+		if s.Name != "" {
+			owner = s.Name
+		}
+		for name, prop := range s.Properties {
+			add(name, owner)
+			walk(prop, owner)
+		}
+		walk(s.Items, owner)
+		for _, variant := range s.OneOf {
+			walk(variant, owner)
+		}
+		for _, variant := range s.AnyOf {
+			walk(variant, owner)
+		}
+		for _, member := range s.AllOf {
+			walk(member, owner)
+		}
+	}
+
+	if doc.Components != nil {
+		for name, s := range doc.Components.Schemas {
+			walk(s, name)
+		}
+	}
+	for _, ep := range doc.Endpoints {
+		owner := endpointKey(ep.Method, ep.Path)
+		for _, p := range ep.Parameters {
+			if p != nil && p.Name != "" {
+				add(p.Name, owner)
+			}
+		}
+		if ep.RequestBody != nil {
+			for _, mt := range ep.RequestBody.Content {
+				if mt != nil {
+					walk(mt.Schema, owner)
+				}
+			}
+		}
+		for _, resp := range ep.Responses {
+			if resp == nil {
+				continue
+			}
+			for _, mt := range resp.Content {
+				if mt != nil {
+					walk(mt.Schema, owner)
+				}
+			}
+		}
+	}
+
+	index := make(map[string][]string, len(owners))
+	for field, set := range owners {
+		list := make([]string, 0, len(set))
+		for owner := range set {
+			list = append(list, owner)
+		}
+		sort.Strings(list)
+		index[field] = list
+	}
+	return index
+}
+
+// TopoSortSchemas returns the names of the component schemas in dependency
+// order: every schema appears after the schemas it references, so leaf types
+// come first. Ties are broken alphabetically. A schema that refers only to
+// itself, such as a tree node, constrains nothing and is accepted; a cycle
+// through two or more schemas makes an order impossible and is reported as an
+// error naming the cycle.
+func TopoSortSchemas(doc *APIDocument) ([]string, error) {
+	if doc.Components == nil {
+		return nil, nil
+	}
+	deps := schemaDependencies(doc)
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(names))
+	var order []string
+	var stack []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for i, n := range stack {
+				if n == name {
+					start = i
+				}
+			}
+			cycle := append(append([]string{}, stack[start:]...), name)
+			return fmt.Errorf("circular schema dependency: %s", strings.Join(cycle, " -> "))
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// schemaDependencies maps each component schema name to the sorted names of
+// the other component schemas it references, directly or through inline
+// sub-schemas. References to the schema itself are left out, as
+// ResolveReferences turns them into a pointer back to the same schema. It
+// gives the same result before and after ResolveReferences.
+func schemaDependencies(doc *APIDocument) map[string][]string {
+	deps := make(map[string][]string)
+	if doc.Components == nil {
+		return deps
+	}
+	for name, schema := range doc.Components.Schemas {
+		found := make(map[string]bool)
+		walkSchemaRefs(schema, true, map[*Schema]bool{}, func(ref string) {
+			if _, ok := doc.Components.Schemas[ref]; ok && ref != name {
+				found[ref] = true
+			}
+		})
+		list := make([]string, 0, len(found))
+		for ref := range found {
+			list = append(list, ref)
+		}
+		sort.Strings(list)
+		deps[name] = list
+	}
+	return deps
+}
+
+// walkSchemaRefs calls visit with the component name of every schema that s
+// refers to. It does not descend into referenced components; root marks the
+// component being inspected so its own name is not reported.
+func walkSchemaRefs(s *Schema, root bool, seen map[*Schema]bool, visit func(name string)) {
+	if s == nil || seen[s] {
+		return
+	}
+	if s.Ref != "" {
+		visit(extractNameFromRef(s.Ref, "schemas"))
+		return
+	}
+	if s.Name != "" && !root {
+		visit(s.Name)
+		return
+	}
+	seen[s] = true
+	for _, prop := range s.Properties {
+		walkSchemaRefs(prop, false, seen, visit)
+	}
+	walkSchemaRefs(s.Items, false, seen, visit)
+	for _, variant := range s.OneOf {
+		walkSchemaRefs(variant, false, seen, visit)
+	}
+	for _, variant := range s.AnyOf {
+		walkSchemaRefs(variant, false, seen, visit)
+	}
+	for _, member := range s.AllOf {
+		walkSchemaRefs(member, false, seen, visit)
+	}
+}
@@ -0,0 +1,64 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTopoSortSchemasSelfReference(t *testing.T) {
+	const spec = `
+title: Tree
+components:
+  schemas:
+    Node:
+      type: object
+      properties:
+        label: {$ref: '#/components/schemas/Label'}
+        children:
+          type: array
+          items: {$ref: '#/components/schemas/Node'}
+    Label: {type: string}
+`
+	doc, err := LoadAPISpecReader(strings.NewReader(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Label", "Node"}
+
+	before, err := TopoSortSchemas(doc)
+	if err != nil {
+		t.Fatalf("before ResolveReferences: %v", err)
+	}
+	if !reflect.DeepEqual(before, want) {
+		t.Errorf("before ResolveReferences: got %v, want %v", before, want)
+	}
+
+	if err := ResolveReferences(doc); err != nil {
+		t.Fatal(err)
+	}
+	after, err := TopoSortSchemas(doc)
+	if err != nil {
+		t.Fatalf("after ResolveReferences: %v", err)
+	}
+	if !reflect.DeepEqual(after, want) {
+		t.Errorf("after ResolveReferences: got %v, want %v", after, want)
+	}
+}
+
+func TestTopoSortSchemasCycle(t *testing.T) {
+	const spec = `
+title: Cycle
+components:
+  schemas:
+    A: {properties: {b: {$ref: '#/components/schemas/B'}}}
+    B: {properties: {a: {$ref: '#/components/schemas/A'}}}
+`
+	doc, err := LoadAPISpecReader(strings.NewReader(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TopoSortSchemas(doc); err == nil || !strings.Contains(err.Error(), "A -> B -> A") {
+		t.Errorf("got error %v, want the cycle A -> B -> A", err)
+	}
+}
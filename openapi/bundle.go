@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// =====================================================
+// Zip Bundles
+// =====================================================
+
+// DefaultBundleRoots are the file names a zip bundle's root spec is looked
+// for under, in order of preference.
+var DefaultBundleRoots = []string{
+	"openapi.yaml", "openapi.yml", "openapi.json",
+	"swagger.yaml", "swagger.yml", "swagger.json",
+}
+
+// loadBundleSpec loads the root spec of the zip archive at zipPath. Refs
+// into other files are later resolved against the other entries of the
+// archive rather than the filesystem.
+func loadBundleSpec(zipPath string, opts LoadOptions) (*APIDocument, error) {
+	data, err := ioutil.ReadFile(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", zipPath, err)
+	}
+	root, err := bundleRoot(archive, opts.BundleRoot)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", zipPath, err)
+	}
+	f, err := archive.Open(root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", zipPath, err)
+	}
+	defer f.Close()
+	doc, err := loadSpecReader(f, root, opts)
+	if err != nil {
+		return nil, err
+	}
+	doc.SourcePath = root
+	doc.sourceFS = archive
+	return doc, nil
+}
+
+// bundleRoot returns the path of the root spec inside archive: root itself
+// when given, and otherwise the shallowest entry named as in
+// DefaultBundleRoots, preferring names listed first.
+func bundleRoot(archive *zip.Reader, root string) (string, error) {
+	if root != "" {
+		root = strings.TrimPrefix(path.Clean(root), "/")
+		if _, err := fs.Stat(archive, root); err != nil {
+			return "", fmt.Errorf("no root spec %s in the archive", root)
+		}
+		return root, nil
+	}
+	best, bestDepth, bestRank := "", 0, 0
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		rank := -1
+		for i, name := range DefaultBundleRoots {
+			if path.Base(f.Name) == name {
+				rank = i
+			}
+		}
+		if rank < 0 {
+			continue
+		}
+		depth := strings.Count(f.Name, "/")
+		if best == "" || depth < bestDepth || depth == bestDepth && rank < bestRank {
+			best, bestDepth, bestRank = f.Name, depth, rank
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no root spec found in the archive; expected one of %s", strings.Join(DefaultBundleRoots, ", "))
+	}
+	return best, nil
+}
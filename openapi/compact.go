@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// =====================================================
+// Compact Rendering
+// =====================================================
+
+// compactTypeNames abbreviates the longer JSON schema type names in
+// RenderCompact output.
+var compactTypeNames = map[string]string{
+	"integer": "int",
+	"boolean": "bool",
+}
+
+// compactTypePattern matches the type names in compactTypeNames as whole
+// words, so schema names containing them are left alone.
+var compactTypePattern = regexp.MustCompile(`\b(integer|boolean)\b`)
+
+// RenderCompact renders doc as one dense line per endpoint for very tight
+// context budgets, e.g.
+//
+//	GET /pets/{id} [id:int(path,req)] -> 200,404
+//
+// Parameters are listed as name:type(location[,req]), a request body as
+// body:Schema, and the response status codes after the arrow. There are no
+// blank lines and no descriptions. Run it after ResolveReferences so that
+// parameter and body types are known.
+func RenderCompact(doc *APIDocument) string {
+	var sb strings.Builder
+	title, version := headerTitleVersion(doc, RenderOptions{})
+	sb.WriteString(fmt.Sprintf("API: %s (%s)\n", title, version))
+	for _, ep := range doc.Endpoints {
+		sb.WriteString(compactEndpoint(ep) + "\n")
+	}
+	return sb.String()
+}
+
+// compactEndpoint renders the RenderCompact line for ep.
+func compactEndpoint(ep Endpoint) string {
+	line := strings.ToUpper(ep.Method) + " " + ep.Path
+
+	params := make([]string, 0, len(ep.Parameters))
+	for _, p := range ep.Parameters {
+		if p == nil {
+			continue
+		}
+		where := p.In
+		if p.Required {
+			where += ",req"
+		}
+		params = append(params, fmt.Sprintf("%s:%s(%s)", p.Name, compactType(parameterType(p)), where))
+	}
+	if len(params) > 0 {
+		line += " [" + strings.Join(params, ", ") + "]"
+	}
+
+	if rb := ep.RequestBody; rb != nil {
+		body := "body"
+		for _, mediaType := range sortedMediaTypes(rb.Content) {
+			if mt := rb.Content[mediaType]; mt != nil && mt.Schema != nil {
+				body += ":" + compactType(schemaSummary(mt.Schema))
+				break
+			}
+		}
+		line += " " + body
+	}
+
+	codes := sortedResponseCodes(ep.Responses)
+	if len(codes) == 0 {
+		line += " -> (none)"
+	} else {
+		line += " -> " + strings.Join(codes, ",")
+	}
+	if ep.Deprecated {
+		line += " DEPRECATED"
+	}
+	return line
+}
+
+// compactType abbreviates the base type of a rendered type such as
+// "integer<int64>" or "array[integer]".
+func compactType(typ string) string {
+	return compactTypePattern.ReplaceAllStringFunc(typ, func(name string) string {
+		return compactTypeNames[name]
+	})
+}
@@ -0,0 +1,255 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// =====================================================
+// Spec Comparison
+// =====================================================
+
+// DiffReport lists what changed between two versions of an API. Endpoints
+// are named by method and path, e.g. "POST /pets".
+type DiffReport struct {
+	Added   []string         `json:"added,omitempty" yaml:"added,omitempty"`
+	Removed []string         `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Changed []EndpointChange `json:"changed,omitempty" yaml:"changed,omitempty"`
+}
+
+// EndpointChange describes how an endpoint present in both versions
+// changed, one entry per change, e.g. "parameter 'tag' now required".
+type EndpointChange struct {
+	Endpoint string   `json:"endpoint" yaml:"endpoint"`
+	Changes  []string `json:"changes" yaml:"changes"`
+}
+
+// Diff compares two versions of an API, matching endpoints by method and
+// path. Added endpoints are listed in the order of newDoc, removed ones in
+// the order of oldDoc. Endpoints in both are compared by their parameters,
+// request body, responses and deprecation. Run it on resolved documents so
+// schemas are compared by content rather than by ref.
+func Diff(oldDoc, newDoc *APIDocument) DiffReport {
+	var report DiffReport
+	oldEndpoints := make(map[string]Endpoint, len(oldDoc.Endpoints))
+	for _, ep := range oldDoc.Endpoints {
+		oldEndpoints[endpointKey(ep.Method, ep.Path)] = ep
+	}
+	newKeys := make(map[string]bool, len(newDoc.Endpoints))
+	for _, ep := range newDoc.Endpoints {
+		key := endpointKey(ep.Method, ep.Path)
+		newKeys[key] = true
+		old, ok := oldEndpoints[key]
+		if !ok {
+			report.Added = append(report.Added, key)
+			continue
+		}
+		if changes := endpointChanges(old, ep); len(changes) > 0 {
+			report.Changed = append(report.Changed, EndpointChange{Endpoint: key, Changes: changes})
+		}
+	}
+	for _, ep := range oldDoc.Endpoints {
+		if key := endpointKey(ep.Method, ep.Path); !newKeys[key] {
+			report.Removed = append(report.Removed, key)
+		}
+	}
+	return report
+}
+
+// RenderDiff renders a DiffReport one line per change, marking added
+// endpoints with +, removed ones with - and changed ones with ~:
+//
+//	$ robot-readme -diff old.json -in new.json -out -
+//	+ POST /pets (added)
+//	- GET /old (removed)
+//	~ PUT /pets (parameter 'tag' now required)
+func RenderDiff(report DiffReport) string {
+	var sb strings.Builder
+	for _, key := range report.Added {
+		sb.WriteString(fmt.Sprintf("+ %s (added)\n", key))
+	}
+	for _, key := range report.Removed {
+		sb.WriteString(fmt.Sprintf("- %s (removed)\n", key))
+	}
+	for _, change := range report.Changed {
+		for _, detail := range change.Changes {
+			sb.WriteString(fmt.Sprintf("~ %s (%s)\n", change.Endpoint, detail))
+		}
+	}
+	if sb.Len() == 0 {
+		return "No changes.\n"
+	}
+	return sb.String()
+}
+
+// endpointChanges describes the differences between two versions of the same
+// endpoint.
+func endpointChanges(old, cur Endpoint) []string {
+	var changes []string
+	changes = append(changes, parameterChanges(old.Parameters, cur.Parameters)...)
+
+	switch {
+	case old.RequestBody == nil && cur.RequestBody != nil:
+		changes = append(changes, "request body added")
+	case old.RequestBody != nil && cur.RequestBody == nil:
+		changes = append(changes, "request body removed")
+	case old.RequestBody != nil:
+		changes = append(changes, contentChanges("request body", old.RequestBody.Content, cur.RequestBody.Content)...)
+	}
+
+	for _, code := range sortedResponseCodes(old.Responses) {
+		if _, ok := cur.Responses[code]; !ok {
+			changes = append(changes, fmt.Sprintf("response %s removed", code))
+		}
+	}
+	for _, code := range sortedResponseCodes(cur.Responses) {
+		oldResp, ok := old.Responses[code]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("response %s added", code))
+			continue
+		}
+		if oldResp != nil && cur.Responses[code] != nil {
+			changes = append(changes, contentChanges("response "+code, oldResp.Content, cur.Responses[code].Content)...)
+		}
+	}
+
+	switch {
+	case !old.Deprecated && cur.Deprecated:
+		changes = append(changes, "now deprecated")
+	case old.Deprecated && !cur.Deprecated:
+		changes = append(changes, "no longer deprecated")
+	}
+	return changes
+}
+
+// parameterChanges describes added and removed parameters and changes to the
+// type or required flag of the parameters in both lists. Parameters are
+// matched by location and name.
+func parameterChanges(old, cur []*Parameter) []string {
+	key := func(p *Parameter) string { return p.In + " " + p.Name }
+	oldParams := make(map[string]*Parameter, len(old))
+	for _, p := range old {
+		if p != nil {
+			oldParams[key(p)] = p
+		}
+	}
+	var changes []string
+	seen := make(map[string]bool, len(cur))
+	for _, p := range cur {
+		if p == nil {
+			continue
+		}
+		seen[key(p)] = true
+		prev, ok := oldParams[key(p)]
+		switch {
+		case !ok && p.Required:
+			changes = append(changes, fmt.Sprintf("new required %s parameter '%s'", p.In, p.Name))
+		case !ok:
+			changes = append(changes, fmt.Sprintf("new %s parameter '%s'", p.In, p.Name))
+		default:
+			if !prev.Required && p.Required {
+				changes = append(changes, fmt.Sprintf("parameter '%s' now required", p.Name))
+			}
+			if prev.Required && !p.Required {
+				changes = append(changes, fmt.Sprintf("parameter '%s' now optional", p.Name))
+			}
+			if before, after := parameterType(prev), parameterType(p); before != after {
+				changes = append(changes, fmt.Sprintf("parameter '%s' type changed from %s to %s", p.Name, before, after))
+			}
+		}
+	}
+	for _, p := range old {
+		if p != nil && !seen[key(p)] {
+			changes = append(changes, fmt.Sprintf("%s parameter '%s' removed", p.In, p.Name))
+		}
+	}
+	return changes
+}
+
+// contentChanges describes media types added to or removed from a request or
+// response body, labeled e.g. "response 200", and schemas whose summary
+// changed.
+func contentChanges(label string, old, cur map[string]*MediaType) []string {
+	var changes []string
+	for _, mediaType := range sortedMediaTypes(old) {
+		if _, ok := cur[mediaType]; !ok {
+			changes = append(changes, fmt.Sprintf("%s no longer offers %s", label, mediaType))
+		}
+	}
+	for _, mediaType := range sortedMediaTypes(cur) {
+		prev, ok := old[mediaType]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s now offers %s", label, mediaType))
+			continue
+		}
+		before, after := mediaTypeSchema(prev), mediaTypeSchema(cur[mediaType])
+		if before != after {
+			changes = append(changes, fmt.Sprintf("%s %s changed from %s to %s", label, mediaType, before, after))
+			continue
+		}
+		changes = append(changes, fieldChanges(label, diffBodySchema(prev), diffBodySchema(cur[mediaType]))...)
+	}
+	return changes
+}
+
+// diffBodySchema returns the object schema whose properties a body carries: its
+// schema, or the items of an array schema.
+func diffBodySchema(mt *MediaType) *Schema {
+	if mt == nil || mt.Schema == nil {
+		return nil
+	}
+	if mt.Schema.Type.Base() == "array" && mt.Schema.Items != nil {
+		return mt.Schema.Items
+	}
+	return mt.Schema
+}
+
+// fieldChanges describes top-level properties added to or removed from a
+// body schema and changes to their type or required flag.
+func fieldChanges(label string, old, cur *Schema) []string {
+	if old == nil || cur == nil {
+		return nil
+	}
+	required := func(s *Schema, name string) bool {
+		for _, r := range s.Required {
+			if r == name {
+				return true
+			}
+		}
+		return false
+	}
+	var changes []string
+	for _, name := range sortedSchemaNames(old.Properties) {
+		if _, ok := cur.Properties[name]; !ok {
+			changes = append(changes, fmt.Sprintf("%s field '%s' removed", label, name))
+		}
+	}
+	for _, name := range sortedSchemaNames(cur.Properties) {
+		prev, ok := old.Properties[name]
+		switch {
+		case !ok && required(cur, name):
+			changes = append(changes, fmt.Sprintf("%s field '%s' added, required", label, name))
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s field '%s' added", label, name))
+		default:
+			if !required(old, name) && required(cur, name) {
+				changes = append(changes, fmt.Sprintf("%s field '%s' now required", label, name))
+			}
+			if required(old, name) && !required(cur, name) {
+				changes = append(changes, fmt.Sprintf("%s field '%s' now optional", label, name))
+			}
+			if before, after := schemaSummary(prev), schemaSummary(cur.Properties[name]); before != after {
+				changes = append(changes, fmt.Sprintf("%s field '%s' type changed from %s to %s", label, name, before, after))
+			}
+		}
+	}
+	return changes
+}
+
+// mediaTypeSchema summarizes the schema of a media type for comparison.
+func mediaTypeSchema(mt *MediaType) string {
+	if mt == nil || mt.Schema == nil {
+		return "no schema"
+	}
+	return schemaSummary(mt.Schema)
+}
@@ -0,0 +1,160 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// =====================================================
+// Example Generation
+// =====================================================
+
+// GenerateExample builds a skeleton value that shows the shape of a payload
+// described by s. Objects become maps of their properties, arrays hold a
+// single item, and scalars use the schema's example, its const, its first
+// enum value or, failing those, a type placeholder.
+// Recursive schemas are cut off with a nil value.
+func GenerateExample(s *Schema) interface{} {
+	return GenerateExampleFor(s, ExampleAny)
+}
+
+// ExampleContext is the direction a generated example travels in, which
+// decides whether readOnly and writeOnly properties belong in it.
+type ExampleContext int
+
+const (
+	// ExampleAny includes every property.
+	ExampleAny ExampleContext = iota
+	// ExampleRequest omits readOnly properties, which clients must not send.
+	ExampleRequest
+	// ExampleResponse omits writeOnly properties, which servers never return.
+	ExampleResponse
+)
+
+// GenerateExampleFor is GenerateExample for a request or response payload.
+func GenerateExampleFor(s *Schema, ctx ExampleContext) interface{} {
+	g := exampleGenerator{ctx: ctx, seen: map[*Schema]bool{}}
+	return g.generate(s)
+}
+
+type exampleGenerator struct {
+	ctx  ExampleContext
+	seen map[*Schema]bool
+}
+
+func (g exampleGenerator) generate(s *Schema) interface{} {
+	if s == nil {
+		return nil
+	}
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Const != nil {
+		return s.Const
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	if g.seen[s] {
+		return nil
+	}
+	g.seen[s] = true
+	defer delete(g.seen, s)
+
+	if len(s.OneOf) > 0 {
+		return g.generate(s.OneOf[0])
+	}
+	if len(s.AnyOf) > 0 {
+		return g.generate(s.AnyOf[0])
+	}
+
+	switch s.Type.Base() {
+	case "array":
+		return []interface{}{g.generate(s.Items)}
+	case "string":
+		return "string"
+	case "integer":
+		return 0
+	case "number":
+		return 0.0
+	case "boolean":
+		return false
+	}
+
+	if s.Type.Base() == "object" || len(s.Properties) > 0 {
+		obj := make(map[string]interface{}, len(s.Properties))
+		for name, prop := range s.Properties {
+			if prop != nil && (prop.ReadOnly && g.ctx == ExampleRequest || prop.WriteOnly && g.ctx == ExampleResponse) {
+				continue
+			}
+			obj[name] = g.generate(prop)
+		}
+		return obj
+	}
+	return nil
+}
+
+// toJSONValue converts values decoded from YAML, whose maps are keyed by
+// interface{}, into values encoding/json can marshal.
+func toJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprintf("%v", key)] = toJSONValue(value)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = toJSONValue(value)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = toJSONValue(item)
+		}
+		return items
+	}
+	return v
+}
+
+// discriminatorValue returns the discriminator value that selects variant.
+// An explicit mapping entry wins, then a const, single enum value or example
+// on the variant's discriminator property, then the variant's component name.
+func discriminatorValue(d *Discriminator, variant *Schema) string {
+	if variant == nil {
+		return ""
+	}
+	if variant.Name != "" {
+		values := make([]string, 0, len(d.Mapping))
+		for value := range d.Mapping {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			if refBaseName(d.Mapping[value]) == variant.Name {
+				return value
+			}
+		}
+	}
+	if prop := variant.Properties[d.PropertyName]; prop != nil {
+		switch {
+		case prop.Const != nil:
+			return fmt.Sprintf("%v", prop.Const)
+		case len(prop.Enum) == 1:
+			return fmt.Sprintf("%v", prop.Enum[0])
+		case prop.Example != nil:
+			return fmt.Sprintf("%v", prop.Example)
+		}
+	}
+	return variant.Name
+}
+
+// refBaseName returns the last path segment of a $ref, e.g. "Pet" for
+// "#/components/schemas/Pet".
+func refBaseName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
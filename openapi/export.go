@@ -0,0 +1,443 @@
+package openapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// =====================================================
+// Structured Exports
+// =====================================================
+
+// RenderCompactJSON emits the structural contract of doc as minified JSON.
+// Descriptions, summaries, examples and defaults are dropped, and schemas
+// that were resolved from a component are written back as a $ref instead of
+// being inlined, so only the shapes remain. Set keepSummaries to retain
+// endpoint summaries.
+func RenderCompactJSON(doc *APIDocument, keepSummaries bool) (string, error) {
+	compact := APIDocument{
+		Title:     doc.Title,
+		Version:   doc.Version,
+		Endpoints: make([]Endpoint, 0, len(doc.Endpoints)),
+	}
+	compact.Servers = compactServers(doc.Servers)
+
+	for _, ep := range doc.Endpoints {
+		c := Endpoint{
+			Path:        ep.Path,
+			Method:      ep.Method,
+			Parameters:  compactParameters(ep.Parameters),
+			RequestBody: compactRequestBody(ep.RequestBody),
+			Responses:   compactResponses(ep.Responses),
+			Servers:     compactServers(ep.Servers),
+			Security:    ep.Security,
+
+			SecurityScopes: ep.SecurityScopes,
+		}
+		if keepSummaries {
+			c.Summary = ep.Summary
+		}
+		compact.Endpoints = append(compact.Endpoints, c)
+	}
+
+	if doc.Components != nil {
+		compact.Components = &Components{
+			Parameters:    make(map[string]*Parameter, len(doc.Components.Parameters)),
+			RequestBodies: make(map[string]*RequestBody, len(doc.Components.RequestBodies)),
+			Responses:     compactResponses(doc.Components.Responses),
+			Schemas:       make(map[string]*Schema, len(doc.Components.Schemas)),
+
+			SecuritySchemes: doc.Components.SecuritySchemes,
+		}
+		for name, p := range doc.Components.Parameters {
+			compact.Components.Parameters[name] = compactParameter(p)
+		}
+		for name, rb := range doc.Components.RequestBodies {
+			compact.Components.RequestBodies[name] = compactRequestBody(rb)
+		}
+		for name, s := range doc.Components.Schemas {
+			compact.Components.Schemas[name] = compactSchemaBody(s)
+		}
+	}
+
+	data, err := json.Marshal(compact)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// compactServers keeps only the server URLs.
+func compactServers(servers []Server) []Server {
+	if len(servers) == 0 {
+		return nil
+	}
+	result := make([]Server, 0, len(servers))
+	for _, server := range servers {
+		result = append(result, Server{URL: server.URL})
+	}
+	return result
+}
+
+func compactParameters(params []*Parameter) []*Parameter {
+	if len(params) == 0 {
+		return nil
+	}
+	result := make([]*Parameter, 0, len(params))
+	for _, p := range params {
+		result = append(result, compactParameter(p))
+	}
+	return result
+}
+
+func compactParameter(p *Parameter) *Parameter {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Description = ""
+	c.Example = nil
+	c.Default = nil
+	c.Schema = compactSchema(p.Schema)
+	c.Items = compactSchema(p.Items)
+	return &c
+}
+
+func compactRequestBody(rb *RequestBody) *RequestBody {
+	if rb == nil {
+		return nil
+	}
+	return &RequestBody{Content: compactContent(rb.Content), Ref: rb.Ref}
+}
+
+func compactResponses(responses map[string]*Response) map[string]*Response {
+	if len(responses) == 0 {
+		return nil
+	}
+	result := make(map[string]*Response, len(responses))
+	for code, r := range responses {
+		if r == nil {
+			result[code] = nil
+			continue
+		}
+		result[code] = &Response{Content: compactContent(r.Content), Ref: r.Ref, Headers: compactHeaders(r.Headers)}
+	}
+	return result
+}
+
+func compactHeaders(headers map[string]*Parameter) map[string]*Parameter {
+	if len(headers) == 0 {
+		return nil
+	}
+	result := make(map[string]*Parameter, len(headers))
+	for name, h := range headers {
+		result[name] = compactParameter(h)
+	}
+	return result
+}
+
+func compactContent(content map[string]*MediaType) map[string]*MediaType {
+	if len(content) == 0 {
+		return nil
+	}
+	result := make(map[string]*MediaType, len(content))
+	for mediaType, mt := range content {
+		if mt == nil {
+			result[mediaType] = nil
+			continue
+		}
+		result[mediaType] = &MediaType{Schema: compactSchema(mt.Schema)}
+	}
+	return result
+}
+
+// compactSchema writes a schema resolved from a component back as a $ref and
+// strips the prose from inline schemas.
+func compactSchema(s *Schema) *Schema {
+	if s == nil {
+		return nil
+	}
+	if s.Name != "" {
+		return &Schema{Ref: "#/components/schemas/" + s.Name}
+	}
+	return compactSchemaBody(s)
+}
+
+// compactSchemaBody strips the prose from s itself, keeping nested component
+// schemas as refs.
+func compactSchemaBody(s *Schema) *Schema {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.Example = nil
+	c.Default = nil
+	c.Items = compactSchema(s.Items)
+	if s.Properties != nil {
+		c.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			c.Properties[name] = compactSchema(prop)
+		}
+	}
+	if s.OneOf != nil {
+		c.OneOf = make([]*Schema, 0, len(s.OneOf))
+		for _, variant := range s.OneOf {
+			c.OneOf = append(c.OneOf, compactSchema(variant))
+		}
+	}
+	if s.AnyOf != nil {
+		c.AnyOf = make([]*Schema, 0, len(s.AnyOf))
+		for _, variant := range s.AnyOf {
+			c.AnyOf = append(c.AnyOf, compactSchema(variant))
+		}
+	}
+	if s.AllOf != nil {
+		c.AllOf = make([]*Schema, 0, len(s.AllOf))
+		for _, member := range s.AllOf {
+			c.AllOf = append(c.AllOf, compactSchema(member))
+		}
+	}
+	return &c
+}
+
+// RenderSpecYAML emits doc itself, normally after ResolveReferences, as
+// YAML: a canonical view with every resolved schema written out in place, for
+// diffing two versions of an API. Map keys come out sorted. A schema that
+// contains itself is written as a $ref at the point of recursion.
+func RenderSpecYAML(doc *APIDocument) (string, error) {
+	data, err := yaml.Marshal(expandedDocument(doc))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// RenderJSON emits doc, normally after ResolveReferences, as indented JSON
+// for programmatic consumers. Like RenderSpecYAML it writes resolved schemas
+// out in place and breaks recursion with a $ref.
+func RenderJSON(doc *APIDocument) (string, error) {
+	data, err := json.MarshalIndent(expandedDocument(doc), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// RenderCSV emits an inventory of doc's endpoints as CSV, one row per
+// endpoint after a header row: method, path, summary, operationId, tags
+// (joined with ", ") and whether the endpoint is deprecated.
+func RenderCSV(doc *APIDocument) (string, error) {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	if err := w.Write([]string{"method", "path", "summary", "operationId", "tag", "deprecated"}); err != nil {
+		return "", err
+	}
+	for _, ep := range doc.Endpoints {
+		row := []string{
+			strings.ToUpper(ep.Method),
+			ep.Path,
+			ep.Summary,
+			ep.OperationID,
+			strings.Join(ep.Tags, ", "),
+			strconv.FormatBool(ep.Deprecated),
+		}
+		if err := w.Write(row); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// expandedDocument returns a copy of doc in which resolved schemas are
+// copied into every place that uses them, so the shared and cyclic pointers
+// left by ResolveReferences can be marshaled. Free-form values decoded from
+// YAML are converted to JSON-compatible maps.
+func expandedDocument(doc *APIDocument) *APIDocument {
+	expanded := *doc
+	expanded.Endpoints = make([]Endpoint, 0, len(doc.Endpoints))
+	for _, ep := range doc.Endpoints {
+		ep.Parameters = expandParameters(ep.Parameters)
+		ep.RequestBody = expandRequestBody(ep.RequestBody)
+		ep.Responses = expandResponses(ep.Responses)
+		expanded.Endpoints = append(expanded.Endpoints, ep)
+	}
+	if doc.Components != nil {
+		components := *doc.Components
+		components.Responses = expandResponses(doc.Components.Responses)
+		if doc.Components.Schemas != nil {
+			components.Schemas = make(map[string]*Schema, len(doc.Components.Schemas))
+			for name, s := range doc.Components.Schemas {
+				components.Schemas[name] = expandSchema(s, map[*Schema]bool{})
+			}
+		}
+		if doc.Components.Parameters != nil {
+			components.Parameters = make(map[string]*Parameter, len(doc.Components.Parameters))
+			for name, p := range doc.Components.Parameters {
+				components.Parameters[name] = expandParameter(p)
+			}
+		}
+		if doc.Components.RequestBodies != nil {
+			components.RequestBodies = make(map[string]*RequestBody, len(doc.Components.RequestBodies))
+			for name, rb := range doc.Components.RequestBodies {
+				components.RequestBodies[name] = expandRequestBody(rb)
+			}
+		}
+		expanded.Components = &components
+	}
+	return &expanded
+}
+
+// jsonValues applies toJSONValue to each of values.
+func jsonValues(values []interface{}) []interface{} {
+	if values == nil {
+		return nil
+	}
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = toJSONValue(v)
+	}
+	return result
+}
+
+func expandParameters(params []*Parameter) []*Parameter {
+	if params == nil {
+		return nil
+	}
+	result := make([]*Parameter, 0, len(params))
+	for _, p := range params {
+		result = append(result, expandParameter(p))
+	}
+	return result
+}
+
+func expandParameter(p *Parameter) *Parameter {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Schema = expandSchema(p.Schema, map[*Schema]bool{})
+	c.Items = expandSchema(p.Items, map[*Schema]bool{})
+	c.Default = toJSONValue(p.Default)
+	c.Example = toJSONValue(p.Example)
+	c.Enum = jsonValues(p.Enum)
+	return &c
+}
+
+func expandRequestBody(rb *RequestBody) *RequestBody {
+	if rb == nil {
+		return nil
+	}
+	c := *rb
+	c.Content = expandContent(rb.Content)
+	return &c
+}
+
+func expandResponses(responses map[string]*Response) map[string]*Response {
+	if responses == nil {
+		return nil
+	}
+	result := make(map[string]*Response, len(responses))
+	for code, r := range responses {
+		if r == nil {
+			result[code] = nil
+			continue
+		}
+		c := *r
+		c.Content = expandContent(r.Content)
+		c.Schema = expandSchema(r.Schema, map[*Schema]bool{})
+		if r.Headers != nil {
+			c.Headers = make(map[string]*Parameter, len(r.Headers))
+			for name, h := range r.Headers {
+				c.Headers[name] = expandParameter(h)
+			}
+		}
+		result[code] = &c
+	}
+	return result
+}
+
+func expandContent(content map[string]*MediaType) map[string]*MediaType {
+	if content == nil {
+		return nil
+	}
+	result := make(map[string]*MediaType, len(content))
+	for mediaType, mt := range content {
+		if mt == nil {
+			result[mediaType] = nil
+			continue
+		}
+		c := *mt
+		c.Schema = expandSchema(mt.Schema, map[*Schema]bool{})
+		c.Example = toJSONValue(mt.Example)
+		if mt.Examples != nil {
+			c.Examples = make(map[string]*Example, len(mt.Examples))
+			for name, ex := range mt.Examples {
+				if ex != nil {
+					exCopy := *ex
+					exCopy.Value = toJSONValue(ex.Value)
+					ex = &exCopy
+				}
+				c.Examples[name] = ex
+			}
+		}
+		result[mediaType] = &c
+	}
+	return result
+}
+
+// expandSchema copies s and the schemas nested in it. ancestors holds the
+// schemas being copied further up; meeting one again means s is recursive,
+// and it is written as a $ref to its component instead.
+func expandSchema(s *Schema, ancestors map[*Schema]bool) *Schema {
+	if s == nil {
+		return nil
+	}
+	if ancestors[s] {
+		if s.Name != "" {
+			return &Schema{Ref: "#/components/schemas/" + s.Name}
+		}
+		return &Schema{Type: s.Type}
+	}
+	ancestors[s] = true
+	defer delete(ancestors, s)
+
+	c := *s
+	c.Example = toJSONValue(s.Example)
+	c.Const = toJSONValue(s.Const)
+	c.Default = toJSONValue(s.Default)
+	c.Enum = jsonValues(s.Enum)
+	c.Items = expandSchema(s.Items, ancestors)
+	if s.Properties != nil {
+		c.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			c.Properties[name] = expandSchema(prop, ancestors)
+		}
+	}
+	if s.OneOf != nil {
+		c.OneOf = make([]*Schema, 0, len(s.OneOf))
+		for _, variant := range s.OneOf {
+			c.OneOf = append(c.OneOf, expandSchema(variant, ancestors))
+		}
+	}
+	if s.AnyOf != nil {
+		c.AnyOf = make([]*Schema, 0, len(s.AnyOf))
+		for _, variant := range s.AnyOf {
+			c.AnyOf = append(c.AnyOf, expandSchema(variant, ancestors))
+		}
+	}
+	if s.AllOf != nil {
+		c.AllOf = make([]*Schema, 0, len(s.AllOf))
+		for _, member := range s.AllOf {
+			c.AllOf = append(c.AllOf, expandSchema(member, ancestors))
+		}
+	}
+	return &c
+}
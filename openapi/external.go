@@ -0,0 +1,297 @@
+package openapi
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// =====================================================
+// External References
+// =====================================================
+
+// externalRefs loads the targets of refs that point into other files, such
+// as "./schemas/pet.yaml#/Pet". Each file is read once, and each schema is
+// decoded once so that repeated refs share a pointer. Files are read from
+// fsys when it is set, such as a zip bundle, and from disk otherwise.
+type externalRefs struct {
+	baseDir string
+	fsys    fs.FS
+	files   map[string]interface{}
+	schemas map[string]*Schema
+}
+
+func newExternalRefs(baseDir string, fsys fs.FS) *externalRefs {
+	return &externalRefs{
+		baseDir: baseDir,
+		fsys:    fsys,
+		files:   make(map[string]interface{}),
+		schemas: make(map[string]*Schema),
+	}
+}
+
+// isExternalRef reports whether ref points into another file rather than
+// the current document. Refs with a URL scheme are left to custom resolvers.
+func isExternalRef(ref string) bool {
+	return ref != "" && !strings.HasPrefix(ref, "#") && !strings.Contains(ref, "://")
+}
+
+// hasExternalRefs reports whether any ref reachable from doc's endpoints
+// points into another file.
+func hasExternalRefs(doc *APIDocument) bool {
+	seen := make(map[*Schema]bool)
+	var inSchema func(s *Schema) bool
+	inSchema = func(s *Schema) bool {
+		if s == nil || seen[s] {
+			return false
+		}
+		seen[s] = true
+		if isExternalRef(s.Ref) || inSchema(s.Items) {
+			return true
+		}
+		for _, prop := range s.Properties {
+			if inSchema(prop) {
+				return true
+			}
+		}
+		for _, variant := range s.OneOf {
+			if inSchema(variant) {
+				return true
+			}
+		}
+		for _, variant := range s.AnyOf {
+			if inSchema(variant) {
+				return true
+			}
+		}
+		for _, member := range s.AllOf {
+			if inSchema(member) {
+				return true
+			}
+		}
+		return false
+	}
+	inContent := func(content map[string]*MediaType) bool {
+		for _, mt := range content {
+			if mt != nil && inSchema(mt.Schema) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, ep := range doc.Endpoints {
+		for _, p := range ep.Parameters {
+			if p != nil && (isExternalRef(p.Ref) || inSchema(p.Schema)) {
+				return true
+			}
+		}
+		if rb := ep.RequestBody; rb != nil && (isExternalRef(rb.Ref) || inContent(rb.Content)) {
+			return true
+		}
+		for _, resp := range ep.Responses {
+			if resp != nil && (isExternalRef(resp.Ref) || inContent(resp.Content)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schema returns the schema an external ref points at, named as described
+// by externalSchemaName.
+func (e *externalRefs) schema(ref string) (*Schema, error) {
+	key := e.absolute(ref, e.baseDir)
+	if s, ok := e.schemas[key]; ok {
+		return s, nil
+	}
+	s := &Schema{}
+	if err := e.decode(ref, s); err != nil {
+		return nil, err
+	}
+	if s.Name == "" {
+		s.Name = externalSchemaName(key)
+	}
+	e.schemas[key] = s
+	return s, nil
+}
+
+// externalSchemaName names the schema behind an absolute external ref: the
+// file name for a whole-file ref, the key for a top-level entry or an entry
+// of a schemas/definitions section, and "" for anything nested deeper, which
+// is rendered by its type instead.
+func externalSchemaName(ref string) string {
+	file, fragment := splitRef(ref)
+	segments := strings.Split(strings.Trim(fragment, "/"), "/")
+	switch {
+	case fragment == "" || fragment == "/":
+		return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	case len(segments) == 1:
+		return unescapePointer(segments[0])
+	}
+	parent := segments[len(segments)-2]
+	if parent == "schemas" || parent == "definitions" {
+		return unescapePointer(segments[len(segments)-1])
+	}
+	return ""
+}
+
+// decode loads the value an external ref points at into out. Refs inside
+// the loaded value are rewritten to absolute file refs first, so they keep
+// pointing into the file they were written in.
+func (e *externalRefs) decode(ref string, out interface{}) error {
+	file, fragment := splitRef(e.absolute(ref, e.baseDir))
+	root, err := e.load(file)
+	if err != nil {
+		return fmt.Errorf("external reference %s: %w", ref, err)
+	}
+	node, err := followPointer(root, fragment)
+	if err != nil {
+		return fmt.Errorf("external reference %s: %w", ref, err)
+	}
+	data, err := yaml.Marshal(e.rebase(node, file))
+	if err != nil {
+		return fmt.Errorf("external reference %s: %w", ref, err)
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("external reference %s: %w", ref, err)
+	}
+	return nil
+}
+
+// load reads and parses a YAML or JSON file, caching the result.
+func (e *externalRefs) load(file string) (interface{}, error) {
+	if root, ok := e.files[file]; ok {
+		return root, nil
+	}
+	var data []byte
+	var err error
+	if e.fsys != nil {
+		data, err = fs.ReadFile(e.fsys, strings.TrimPrefix(file, "/"))
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var root interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	e.files[file] = root
+	return root, nil
+}
+
+// absolute makes the file part of ref absolute, resolving it against dir. A
+// ref into the current document is returned as is. Within fsys, paths are
+// slash-separated and absolute to its root, e.g. "/schemas/pet.yaml".
+func (e *externalRefs) absolute(ref, dir string) string {
+	file, fragment := splitRef(ref)
+	if file == "" {
+		return ref
+	}
+	switch {
+	case e.fsys != nil && strings.HasPrefix(file, "/"):
+		file = path.Clean(file)
+	case e.fsys != nil:
+		file = path.Join("/", dir, file)
+	case !filepath.IsAbs(file):
+		file = filepath.Join(dir, file)
+	}
+	if e.fsys == nil {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+	}
+	if fragment == "" {
+		return file
+	}
+	return file + "#" + fragment
+}
+
+// rebase returns a copy of node in which every $ref is made absolute
+// relative to file: "#/Pet" becomes "/abs/file.yaml#/Pet", and "other.yaml"
+// is resolved against file's directory.
+func (e *externalRefs) rebase(node interface{}, file string) interface{} {
+	switch v := node.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				switch {
+				case strings.HasPrefix(ref, "#"):
+					value = file + ref
+				case isExternalRef(ref):
+					value = e.absolute(ref, e.dir(file))
+				}
+				m[key] = value
+				continue
+			}
+			m[key] = e.rebase(value, file)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = e.rebase(item, file)
+		}
+		return items
+	}
+	return node
+}
+
+// dir returns the directory of an absolute file path from absolute.
+func (e *externalRefs) dir(file string) string {
+	if e.fsys != nil {
+		return path.Dir(file)
+	}
+	return filepath.Dir(file)
+}
+
+// splitRef splits "file.yaml#/a/b" into "file.yaml" and "/a/b".
+func splitRef(ref string) (file, fragment string) {
+	if i := strings.Index(ref, "#"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// followPointer walks a JSON pointer such as "/components/schemas/Pet"
+// through a parsed YAML or JSON document.
+func followPointer(root interface{}, pointer string) (interface{}, error) {
+	node := root
+	if pointer == "" || pointer == "/" {
+		return node, nil
+	}
+	for _, segment := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		segment = unescapePointer(segment)
+		switch v := node.(type) {
+		case map[interface{}]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return nil, fmt.Errorf("no %q in %s", segment, pointer)
+			}
+			node = next
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("no index %q in %s", segment, pointer)
+			}
+			node = v[i]
+		default:
+			return nil, fmt.Errorf("cannot descend into %q in %s", segment, pointer)
+		}
+	}
+	return node, nil
+}
+
+// unescapePointer decodes the ~1 and ~0 escapes of a JSON pointer segment.
+func unescapePointer(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
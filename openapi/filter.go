@@ -0,0 +1,67 @@
+package openapi
+
+import "strings"
+
+// =====================================================
+// Endpoint Filtering
+// =====================================================
+
+// FilterWithRequestBody returns a copy of doc that keeps only the endpoints
+// accepting a request body, including bodies synthesized from Swagger 2.0
+// body and formData parameters.
+func FilterWithRequestBody(doc *APIDocument) *APIDocument {
+	return filterEndpoints(doc, func(ep Endpoint) bool {
+		return ep.RequestBody != nil
+	})
+}
+
+// FilterWithoutDeprecated returns a copy of doc without its deprecated
+// endpoints.
+func FilterWithoutDeprecated(doc *APIDocument) *APIDocument {
+	return filterEndpoints(doc, func(ep Endpoint) bool {
+		return !ep.Deprecated
+	})
+}
+
+// FilterEndpoints returns a copy of doc that keeps only the endpoints whose
+// path starts with one of prefixes, e.g. "/pets" keeps /pets and
+// /pets/{id}. With no prefixes every endpoint is kept.
+func FilterEndpoints(doc *APIDocument, prefixes []string) *APIDocument {
+	if len(prefixes) == 0 {
+		return filterEndpoints(doc, func(Endpoint) bool { return true })
+	}
+	return filterEndpoints(doc, func(ep Endpoint) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(ep.Path, prefix) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FilterByMethod returns a copy of doc that keeps only the endpoints whose
+// HTTP method is one of methods, compared case-insensitively, e.g. GET and
+// HEAD for a read-only summary.
+func FilterByMethod(doc *APIDocument, methods []string) *APIDocument {
+	allowed := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		allowed[strings.ToUpper(strings.TrimSpace(method))] = true
+	}
+	return filterEndpoints(doc, func(ep Endpoint) bool {
+		return allowed[strings.ToUpper(ep.Method)]
+	})
+}
+
+// filterEndpoints returns a shallow copy of doc holding only the endpoints
+// for which keep returns true.
+func filterEndpoints(doc *APIDocument, keep func(Endpoint) bool) *APIDocument {
+	filtered := *doc
+	filtered.Endpoints = make([]Endpoint, 0, len(doc.Endpoints))
+	for _, ep := range doc.Endpoints {
+		if keep(ep) {
+			filtered.Endpoints = append(filtered.Endpoints, ep)
+		}
+	}
+	return &filtered
+}
@@ -0,0 +1,114 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterWithRequestBodySharedBodyParameters(t *testing.T) {
+	doc := loadTestSpec(t, `
+swagger: "2.0"
+info: {title: Pets, version: "1"}
+paths:
+  /pets:
+    get:
+      parameters:
+        - $ref: '#/parameters/Limit'
+      responses: {'200': {description: ok}}
+    post:
+      parameters:
+        - $ref: '#/parameters/PetBody'
+      responses: {'201': {description: created}}
+  /pets/{id}/photo:
+    parameters:
+      - $ref: '#/parameters/Photo'
+    put:
+      responses: {'204': {description: uploaded}}
+parameters:
+  PetBody:
+    name: body
+    in: body
+    required: true
+    schema: {$ref: '#/definitions/Pet'}
+  Photo: {name: photo, in: formData, type: file}
+  Limit: {name: limit, in: query, type: integer}
+definitions:
+  Pet: {type: object, properties: {name: {type: string}}}
+`)
+	filtered := FilterWithRequestBody(doc)
+	if len(filtered.Endpoints) != 2 {
+		t.Fatalf("got %d endpoints with a request body, want 2", len(filtered.Endpoints))
+	}
+
+	post := filtered.Endpoints[0]
+	if post.Method != "POST" || post.Path != "/pets" {
+		t.Fatalf("got %s %s first, want POST /pets", post.Method, post.Path)
+	}
+	if len(post.Parameters) != 0 {
+		t.Errorf("POST /pets kept %d parameters, want the body parameter moved to the request body", len(post.Parameters))
+	}
+	if mt := post.RequestBody.Content["application/json"]; mt == nil || mt.Schema == nil || mt.Schema.Name != "Pet" {
+		t.Errorf("POST /pets request body is %+v, want application/json Pet", post.RequestBody.Content)
+	}
+
+	put := filtered.Endpoints[1]
+	if mt := put.RequestBody.Content["multipart/form-data"]; mt == nil || mt.Schema.Properties["photo"] == nil {
+		t.Errorf("PUT %s request body is %+v, want a multipart/form-data photo field", put.Path, put.RequestBody.Content)
+	}
+}
+
+func TestFilterByMethod(t *testing.T) {
+	doc := &APIDocument{
+		Title: "Pets",
+		Endpoints: []Endpoint{
+			{Method: "GET", Path: "/pets"},
+			{Method: "POST", Path: "/pets"},
+			{Method: "get", Path: "/pets/{id}"},
+			{Method: "DELETE", Path: "/pets/{id}"},
+			{Method: "HEAD", Path: "/pets"},
+		},
+	}
+	filtered := FilterByMethod(doc, strings.Split("GET", ","))
+	var kept []string
+	for _, ep := range filtered.Endpoints {
+		kept = append(kept, ep.Method+" "+ep.Path)
+	}
+	if want := "GET /pets, get /pets/{id}"; strings.Join(kept, ", ") != want {
+		t.Errorf("got %v, want %s", kept, want)
+	}
+	if filtered.Title != doc.Title {
+		t.Errorf("got title %q, want the rest of the document kept", filtered.Title)
+	}
+	if len(doc.Endpoints) != 5 {
+		t.Errorf("the original document now has %d endpoints, want it untouched", len(doc.Endpoints))
+	}
+}
+
+func TestDeprecatedEndpoints(t *testing.T) {
+	doc := loadTestSpec(t, `
+swagger: "2.0"
+info: {title: Pets, version: "1"}
+paths:
+  /pets:
+    get: {responses: {'200': {description: ok}}}
+  /pets/legacy:
+    get:
+      deprecated: true
+      responses: {'200': {description: ok}}
+`)
+	text := RenderText(doc)
+	if !strings.Contains(text, "ENDPOINT: GET /pets/legacy [DEPRECATED]") {
+		t.Errorf("want the deprecated endpoint marked, got:\n%s", text)
+	}
+	if strings.Contains(text, "ENDPOINT: GET /pets [DEPRECATED]") {
+		t.Errorf("want only the deprecated endpoint marked, got:\n%s", text)
+	}
+
+	filtered := FilterWithoutDeprecated(doc)
+	if len(filtered.Endpoints) != 1 || filtered.Endpoints[0].Path != "/pets" {
+		t.Errorf("got %+v, want only GET /pets", filtered.Endpoints)
+	}
+	if text := RenderText(filtered); strings.Contains(text, "/pets/legacy") {
+		t.Errorf("want the deprecated endpoint removed, got:\n%s", text)
+	}
+}
@@ -0,0 +1,69 @@
+package openapi
+
+import "fmt"
+
+// =====================================================
+// Library Entry Point
+// =====================================================
+
+// Output formats accepted by Options.Format.
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+	FormatSpecYAML = "spec-yaml"
+	FormatCSV      = "csv"
+)
+
+// Options configures Generate. The zero value loads the spec, resolves its
+// references and renders plain text.
+type Options struct {
+	// Format is one of FormatText (the default), FormatMarkdown, FormatJSON,
+	// FormatSpecYAML or FormatCSV.
+	Format string
+	// SkipResolve renders the document with its $refs left unresolved.
+	SkipResolve bool
+	// Load and Render customize the loading and, for FormatText, rendering
+	// stages.
+	Load   LoadOptions
+	Render RenderOptions
+}
+
+// Generate loads the spec at path, resolves its references, normalizes it
+// with Normalize and renders it in one call, returning the output. Errors
+// say which stage failed.
+func Generate(path string, opts Options) (string, error) {
+	doc, err := LoadAPISpecWithOptions(path, opts.Load)
+	if err != nil {
+		return "", fmt.Errorf("loading spec %s: %w", path, err)
+	}
+	if !opts.SkipResolve {
+		if err := ResolveReferences(doc); err != nil {
+			return "", fmt.Errorf("resolving references in %s: %w", path, err)
+		}
+	}
+	Normalize(doc)
+
+	var out string
+	switch opts.Format {
+	case "", FormatText:
+		if err := opts.Render.Validate(); err != nil {
+			return "", fmt.Errorf("rendering %s: %w", path, err)
+		}
+		out = RenderTextWithOptions(doc, opts.Render)
+	case FormatMarkdown:
+		out = RenderMarkdown(doc)
+	case FormatJSON:
+		out, err = RenderJSON(doc)
+	case FormatSpecYAML:
+		out, err = RenderSpecYAML(doc)
+	case FormatCSV:
+		out, err = RenderCSV(doc)
+	default:
+		return "", fmt.Errorf("unknown format %q", opts.Format)
+	}
+	if err != nil {
+		return "", fmt.Errorf("rendering %s: %w", path, err)
+	}
+	return out, nil
+}
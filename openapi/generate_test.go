@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestSpec writes spec to a file in a temporary directory and returns
+// its path.
+func writeTestSpec(t *testing.T, name, spec string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGenerateCSV(t *testing.T) {
+	path := writeTestSpec(t, "openapi.yaml", `
+openapi: 3.0.0
+info: {title: Pets, version: "1"}
+paths:
+  /pets:
+    get:
+      summary: List pets, newest first
+      operationId: listPets
+      tags: [pets]
+      responses: {'200': {description: ok}}
+`)
+	out, err := Generate(path, Options{Format: FormatCSV})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "method,path,summary,operationId,tag,deprecated\nGET,/pets,\"List pets, newest first\",listPets,pets,false\n"
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestGenerateNormalizes(t *testing.T) {
+	path := writeTestSpec(t, "api.yaml", `
+title: Pets
+endpoints:
+  - {path: " /pets/{id} ", method: delete}
+  - {path: /pets, method: post}
+  - path: /pets
+    method: get
+    parameters:
+      - {name: sort, in: query}
+      - {name: limit, in: query}
+`)
+	out, err := Generate(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var headers []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "ENDPOINT: ") {
+			headers = append(headers, strings.TrimPrefix(line, "ENDPOINT: "))
+		}
+	}
+	if want := "GET /pets, POST /pets, DELETE /pets/{id}"; strings.Join(headers, ", ") != want {
+		t.Errorf("got endpoints %v, want %s", headers, want)
+	}
+	if limit, sort := strings.Index(out, "- limit"), strings.Index(out, "- sort"); limit < 0 || sort < limit {
+		t.Errorf("want parameters sorted by name, got:\n%s", out)
+	}
+}
@@ -0,0 +1,270 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// =====================================================
+// Linting
+// =====================================================
+
+// Severity levels reported by LintDocument and Validate.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Issue is a single finding about the quality of an API document.
+// Path and Method are empty for document-wide findings.
+type Issue struct {
+	Severity string `json:"severity" yaml:"severity"`
+	Path     string `json:"path,omitempty" yaml:"path,omitempty"`
+	Method   string `json:"method,omitempty" yaml:"method,omitempty"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// String formats the issue as "[severity] METHOD /path: message".
+func (i Issue) String() string {
+	switch {
+	case i.Path == "":
+		return fmt.Sprintf("[%s] %s", i.Severity, i.Message)
+	case i.Method == "":
+		return fmt.Sprintf("[%s] %s: %s", i.Severity, i.Path, i.Message)
+	}
+	return fmt.Sprintf("[%s] %s %s: %s", i.Severity, strings.ToUpper(i.Method), i.Path, i.Message)
+}
+
+// LintDocument checks doc for common spec mistakes that would mislead a
+// reader of the rendered output. It never modifies the document.
+func LintDocument(doc *APIDocument) []Issue {
+	var issues []Issue
+	for _, ep := range doc.Endpoints {
+		issues = append(issues, lintPathParameterLocations(ep)...)
+		issues = append(issues, lintRequiredWithoutDefault(ep)...)
+		issues = append(issues, lintSuccessResponses(ep)...)
+	}
+	issues = append(issues, lintParameterNaming(doc)...)
+	issues = append(issues, lintTrailingSlashPaths(doc)...)
+	return issues
+}
+
+// Validate reports under-documented endpoints in doc: a missing summary or
+// description, and responses without a description. It is meant as a docs
+// quality gate, so an empty result means the docs passed.
+func Validate(doc *APIDocument) []Issue {
+	var issues []Issue
+	for _, ep := range doc.Endpoints {
+		report := func(message string) {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Path:     ep.Path,
+				Method:   ep.Method,
+				Message:  message,
+			})
+		}
+		if minifyText(ep.Summary) == "" {
+			report("missing summary")
+		}
+		if minifyText(ep.Description) == "" {
+			report("missing description")
+		}
+		for _, code := range sortedResponseCodes(ep.Responses) {
+			if resp := ep.Responses[code]; resp == nil || minifyText(resp.Description) == "" {
+				report(fmt.Sprintf("response %s has no description", code))
+			}
+		}
+	}
+	return issues
+}
+
+// lintPathParameterLocations flags parameters named after a path placeholder
+// that are declared somewhere other than the path, e.g. an {id} placeholder
+// with an "id" query parameter.
+func lintPathParameterLocations(ep Endpoint) []Issue {
+	placeholders := make(map[string]bool)
+	for _, name := range pathPlaceholders(ep.Path) {
+		placeholders[name] = true
+	}
+
+	var issues []Issue
+	for _, p := range ep.Parameters {
+		if p == nil || !placeholders[p.Name] || p.In == "path" {
+			continue
+		}
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Path:     ep.Path,
+			Method:   ep.Method,
+			Message: fmt.Sprintf("parameter %q matches path placeholder {%s} but is declared in %q, not \"path\"",
+				p.Name, p.Name, p.In),
+		})
+	}
+	return issues
+}
+
+// lintRequiredWithoutDefault notes required parameters that have no default
+// value. Every client must always send them, so renaming or removing one is a
+// breaking change. Path parameters are skipped since they are always
+// required.
+func lintRequiredWithoutDefault(ep Endpoint) []Issue {
+	var issues []Issue
+	for _, p := range ep.Parameters {
+		if p == nil || !p.Required || p.In == "path" || p.Default != nil {
+			continue
+		}
+		if p.Schema != nil && p.Schema.Default != nil {
+			continue
+		}
+		issues = append(issues, Issue{
+			Severity: SeverityInfo,
+			Path:     ep.Path,
+			Method:   ep.Method,
+			Message:  fmt.Sprintf("parameter %q stability: required, no default — clients must always provide it", p.Name),
+		})
+	}
+	return issues
+}
+
+// lintSuccessResponses flags endpoints that declare no responses at all, or
+// only error responses, leaving the shape of a successful call unknown.
+func lintSuccessResponses(ep Endpoint) []Issue {
+	if len(ep.Responses) == 0 {
+		return []Issue{{
+			Severity: SeverityWarning,
+			Path:     ep.Path,
+			Method:   ep.Method,
+			Message:  "endpoint declares no responses",
+		}}
+	}
+	for code := range ep.Responses {
+		if strings.HasPrefix(code, "2") {
+			return nil
+		}
+	}
+	return []Issue{{
+		Severity: SeverityWarning,
+		Path:     ep.Path,
+		Method:   ep.Method,
+		Message:  "endpoint declares no 2xx response",
+	}}
+}
+
+// pathPlaceholders returns the names of the {placeholders} in a path template,
+// in the order they appear.
+func pathPlaceholders(path string) []string {
+	var names []string
+	for {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			return names
+		}
+		end := strings.Index(path[start:], "}")
+		if end < 0 {
+			return names
+		}
+		names = append(names, path[start+1:start+end])
+		path = path[start+end+1:]
+	}
+}
+
+// lintTrailingSlashPaths flags paths declared both with and without a
+// trailing slash, which NormalizePaths can merge.
+func lintTrailingSlashPaths(doc *APIDocument) []Issue {
+	paths := make(map[string]bool, len(doc.Endpoints))
+	for _, ep := range doc.Endpoints {
+		paths[ep.Path] = true
+	}
+	var issues []Issue
+	reported := make(map[string]bool)
+	for _, ep := range doc.Endpoints {
+		canonical := strings.TrimSuffix(ep.Path, "/")
+		if canonical == ep.Path || canonical == "" || !paths[canonical] || reported[ep.Path] {
+			continue
+		}
+		reported[ep.Path] = true
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Path:     ep.Path,
+			Message:  fmt.Sprintf("path differs from %s only by a trailing slash", canonical),
+		})
+	}
+	return issues
+}
+
+// Naming conventions recognized by lintParameterNaming.
+const (
+	conventionCamel  = "camelCase"
+	conventionSnake  = "snake_case"
+	conventionKebab  = "kebab-case"
+	conventionPascal = "PascalCase"
+)
+
+// lintParameterNaming reports parameter names that stray from the naming
+// convention most parameters in the document follow. Header parameters are
+// skipped because HTTP headers have their own conventions, and single
+// lowercase words fit every convention.
+func lintParameterNaming(doc *APIDocument) []Issue {
+	counts := make(map[string]int)
+	byConvention := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, ep := range doc.Endpoints {
+		for _, p := range ep.Parameters {
+			if p == nil || p.In == "header" || seen[p.Name] {
+				continue
+			}
+			seen[p.Name] = true
+			if convention := namingConvention(p.Name); convention != "" {
+				counts[convention]++
+				byConvention[convention] = append(byConvention[convention], p.Name)
+			}
+		}
+	}
+	if len(counts) < 2 {
+		return nil
+	}
+
+	conventions := make([]string, 0, len(counts))
+	for convention := range counts {
+		conventions = append(conventions, convention)
+	}
+	sort.Slice(conventions, func(i, j int) bool {
+		if counts[conventions[i]] != counts[conventions[j]] {
+			return counts[conventions[i]] > counts[conventions[j]]
+		}
+		return conventions[i] < conventions[j]
+	})
+
+	dominant := conventions[0]
+	var outliers []string
+	for _, convention := range conventions[1:] {
+		names := byConvention[convention]
+		sort.Strings(names)
+		outliers = append(outliers, fmt.Sprintf("%s (%s)", strings.Join(names, ", "), convention))
+	}
+	return []Issue{{
+		Severity: SeverityInfo,
+		Message: fmt.Sprintf("parameter names mix naming conventions; most use %s (%d), outliers: %s",
+			dominant, counts[dominant], strings.Join(outliers, "; ")),
+	}}
+}
+
+// namingConvention classifies an identifier, returning "" for names that
+// don't commit to a convention, such as a single lowercase word.
+func namingConvention(name string) string {
+	hasUpper := strings.IndexFunc(name, unicode.IsUpper) >= 0
+	switch {
+	case strings.Contains(name, "_"):
+		return conventionSnake
+	case strings.Contains(name, "-"):
+		return conventionKebab
+	case name != "" && unicode.IsUpper(rune(name[0])):
+		return conventionPascal
+	case hasUpper:
+		return conventionCamel
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// =====================================================
+// Markdown Rendering
+// =====================================================
+
+// RenderMarkdown produces the same documentation as RenderText formatted as
+// Markdown, for chat UIs that render it: a heading per endpoint, a table of
+// parameters and fenced blocks for request and response schemas.
+func RenderMarkdown(doc *APIDocument) string {
+	var sb strings.Builder
+	opts := RenderOptions{}
+
+	title, version := headerTitleVersion(doc, opts)
+	sb.WriteString(fmt.Sprintf("# %s (%s)\n\n", title, version))
+	if doc.Description != "" {
+		sb.WriteString(doc.Description + "\n\n")
+	}
+	if len(doc.Servers) > 0 {
+		sb.WriteString(fmt.Sprintf("**Servers:** %s\n\n", serverList(doc.Servers)))
+	}
+
+	for _, ep := range doc.Endpoints {
+		sb.WriteString(fmt.Sprintf("## %s %s", strings.ToUpper(ep.Method), ep.Path))
+		if ep.Deprecated {
+			sb.WriteString(" (deprecated)")
+		}
+		sb.WriteString("\n\n")
+		if ep.Summary != "" {
+			sb.WriteString(minifyText(ep.Summary) + "\n\n")
+		}
+		if desc := formatDescription(ep.Description, opts.MaxDescriptionLen); desc != "" {
+			sb.WriteString(desc + "\n\n")
+		}
+
+		if len(ep.Parameters) > 0 {
+			sb.WriteString("**Parameters**\n\n")
+			sb.WriteString("| Name | Type | In | Required | Description |\n")
+			sb.WriteString("| --- | --- | --- | --- | --- |\n")
+			for _, p := range ep.Parameters {
+				if p == nil {
+					continue
+				}
+				pType := parameterType(p) + enumNote(parameterEnum(p))
+				desc := minifyText(p.Description) + deprecationNote(p.Deprecated, p.ReplacedBy)
+				sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n",
+					markdownCell(p.Name), markdownCell(pType), markdownCell(p.In),
+					yesNo(p.Required), markdownCell(strings.TrimSpace(desc))))
+			}
+			sb.WriteString("\n")
+		}
+
+		if ep.RequestBody != nil {
+			sb.WriteString("**Request body**")
+			if desc := formatDescription(ep.RequestBody.Description, opts.MaxDescriptionLen); desc != "" {
+				sb.WriteString(": " + desc)
+			}
+			sb.WriteString("\n\n")
+			requestOpts := opts
+			requestOpts.inRequest = true
+			writeFenced(&sb, func(block *strings.Builder) {
+				renderContentSchemas(block, ep.RequestBody.Content, "", requestOpts)
+				renderFormFields(block, ep.RequestBody.Content, requestOpts)
+			})
+		}
+
+		if len(ep.Responses) > 0 {
+			sb.WriteString("**Responses**\n\n")
+			for _, code := range sortedResponseCodes(ep.Responses) {
+				resp := ep.Responses[code]
+				if resp == nil {
+					continue
+				}
+				sb.WriteString(fmt.Sprintf("- `%s`: %s\n", code, minifyText(resp.Description)))
+			}
+			sb.WriteString("\n")
+			for _, code := range sortedResponseCodes(ep.Responses) {
+				resp := ep.Responses[code]
+				if resp == nil || len(resp.Content) == 0 {
+					continue
+				}
+				sb.WriteString(fmt.Sprintf("`%s` body:\n\n", code))
+				writeFenced(&sb, func(block *strings.Builder) {
+					renderContentSchemas(block, resp.Content, "", opts)
+				})
+			}
+		}
+	}
+	return sb.String()
+}
+
+// writeFenced writes the output of render inside a fenced code block, or
+// nothing when render writes nothing.
+func writeFenced(sb *strings.Builder, render func(block *strings.Builder)) {
+	var block strings.Builder
+	render(&block)
+	if block.Len() == 0 {
+		return
+	}
+	sb.WriteString("```\n")
+	sb.WriteString(block.String())
+	sb.WriteString("```\n\n")
+}
+
+// markdownCell escapes text for use inside a Markdown table cell.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(minifyText(text), "|", "\\|")
+}
@@ -0,0 +1,223 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// =====================================================
+// Document Merging
+// =====================================================
+
+// MergeOptions customizes MergeDocumentsWithOptions.
+type MergeOptions struct {
+	// KeepConflicts keeps every endpoint when two documents declare the same
+	// method and path, marking each with the document it came from, and the
+	// first of two different schemas sharing a name, instead of failing the
+	// merge.
+	KeepConflicts bool
+}
+
+// MergeDocuments combines several documents, e.g. one per service, into a
+// single document. See MergeDocumentsWithOptions.
+func MergeDocuments(docs ...*APIDocument) (*APIDocument, error) {
+	return MergeDocumentsWithOptions(MergeOptions{}, docs...)
+}
+
+// MergeDocumentsWithOptions combines docs into a single document. The title,
+// version, description and default media types come from the first document;
+// endpoints of a document with different defaults keep them as their own
+// consumes/produces lists. Servers and endpoints are concatenated in order,
+// with duplicate server URLs dropped, and component maps are unioned, keeping
+// the first definition of a name.
+//
+// Two documents declaring the same method and path is a conflict, and so is
+// two documents defining different schemas under the same name. By default
+// either is reported as an error naming the endpoint or schema and both
+// documents. With opts.KeepConflicts both endpoints are kept and each is
+// marked with its Source, the title of the document that declared it, and
+// the first schema definition wins.
+func MergeDocumentsWithOptions(opts MergeOptions, docs ...*APIDocument) (*APIDocument, error) {
+	merged := &APIDocument{}
+	if len(docs) == 0 {
+		return merged, nil
+	}
+	first := docs[0]
+	merged.Title = first.Title
+	merged.Version = first.Version
+	merged.Description = first.Description
+	merged.Deprecated = first.Deprecated
+	merged.ReplacedBy = first.ReplacedBy
+	merged.TermsOfService = first.TermsOfService
+	merged.Consumes = first.Consumes
+	merged.Produces = first.Produces
+
+	seenServers := make(map[string]bool)
+	// owners maps each endpoint key to the index in merged.Endpoints of its
+	// first declaration and the document that declared it.
+	type owner struct {
+		index int
+		doc   int
+	}
+	owners := make(map[string]owner)
+	// schemaOwners maps each component schema name to the document that
+	// defined it first.
+	schemaOwners := make(map[string]int)
+	for i, doc := range docs {
+		for _, server := range doc.Servers {
+			if !seenServers[server.URL] {
+				seenServers[server.URL] = true
+				merged.Servers = append(merged.Servers, server)
+			}
+		}
+
+		for _, ep := range doc.Endpoints {
+			ep = pinMediaTypes(ep, doc, merged)
+			key := endpointKey(ep.Method, ep.Path)
+			prev, ok := owners[key]
+			if !ok {
+				owners[key] = owner{index: len(merged.Endpoints), doc: i}
+				merged.Endpoints = append(merged.Endpoints, ep)
+				continue
+			}
+			if !opts.KeepConflicts {
+				return nil, fmt.Errorf("conflicting endpoint %s declared in both %s and %s",
+					key, documentLabel(docs[prev.doc], prev.doc), documentLabel(doc, i))
+			}
+			merged.Endpoints[prev.index].Source = documentLabel(docs[prev.doc], prev.doc)
+			ep.Source = documentLabel(doc, i)
+			merged.Endpoints = append(merged.Endpoints, ep)
+		}
+
+		if doc.Components != nil && !opts.KeepConflicts {
+			for _, name := range sortedSchemaNames(doc.Components.Schemas) {
+				prev, ok := schemaOwners[name]
+				if !ok {
+					schemaOwners[name] = i
+					continue
+				}
+				if !reflect.DeepEqual(merged.Components.Schemas[name], doc.Components.Schemas[name]) {
+					return nil, fmt.Errorf("conflicting schema %s defined differently in %s and %s",
+						name, documentLabel(docs[prev], prev), documentLabel(doc, i))
+				}
+			}
+		}
+		mergeComponents(merged, doc.Components)
+	}
+	return merged, nil
+}
+
+// sortedSchemaNames returns the names of schemas in alphabetical order, so
+// the first conflict reported is the same on every run.
+func sortedSchemaNames(schemas map[string]*Schema) []string {
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pinMediaTypes gives ep the consumes/produces defaults of its own document
+// when they differ from the merged document's, so the endpoint keeps the media
+// types it inherited before the merge.
+func pinMediaTypes(ep Endpoint, doc, merged *APIDocument) Endpoint {
+	if len(ep.Consumes) == 0 && !equalStrings(doc.Consumes, merged.Consumes) {
+		ep.Consumes = doc.Consumes
+	}
+	if len(ep.Produces) == 0 && !equalStrings(doc.Produces, merged.Produces) {
+		ep.Produces = doc.Produces
+	}
+	return ep
+}
+
+// equalStrings reports whether a and b hold the same strings in order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeComponents adds the components of src that merged does not define yet.
+func mergeComponents(merged *APIDocument, src *Components) {
+	if src == nil {
+		return
+	}
+	if merged.Components == nil {
+		merged.Components = &Components{}
+	}
+	dst := merged.Components
+	if len(src.Schemas) > 0 && dst.Schemas == nil {
+		dst.Schemas = make(map[string]*Schema)
+	}
+	for name, s := range src.Schemas {
+		if _, ok := dst.Schemas[name]; !ok {
+			dst.Schemas[name] = s
+		}
+	}
+	if len(src.Parameters) > 0 && dst.Parameters == nil {
+		dst.Parameters = make(map[string]*Parameter)
+	}
+	for name, p := range src.Parameters {
+		if _, ok := dst.Parameters[name]; !ok {
+			dst.Parameters[name] = p
+		}
+	}
+	if len(src.RequestBodies) > 0 && dst.RequestBodies == nil {
+		dst.RequestBodies = make(map[string]*RequestBody)
+	}
+	for name, rb := range src.RequestBodies {
+		if _, ok := dst.RequestBodies[name]; !ok {
+			dst.RequestBodies[name] = rb
+		}
+	}
+	if len(src.Responses) > 0 && dst.Responses == nil {
+		dst.Responses = make(map[string]*Response)
+	}
+	for name, r := range src.Responses {
+		if _, ok := dst.Responses[name]; !ok {
+			dst.Responses[name] = r
+		}
+	}
+	if len(src.Examples) > 0 && dst.Examples == nil {
+		dst.Examples = make(map[string]*Example)
+	}
+	for name, ex := range src.Examples {
+		if _, ok := dst.Examples[name]; !ok {
+			dst.Examples[name] = ex
+		}
+	}
+	if len(src.SecuritySchemes) > 0 && dst.SecuritySchemes == nil {
+		dst.SecuritySchemes = make(map[string]*SecurityScheme)
+	}
+	for name, scheme := range src.SecuritySchemes {
+		if _, ok := dst.SecuritySchemes[name]; !ok {
+			dst.SecuritySchemes[name] = scheme
+		}
+	}
+	if len(src.Headers) > 0 && dst.Headers == nil {
+		dst.Headers = make(map[string]*Parameter)
+	}
+	for name, h := range src.Headers {
+		if _, ok := dst.Headers[name]; !ok {
+			dst.Headers[name] = h
+		}
+	}
+}
+
+// documentLabel names a document in merge messages by its title, falling back
+// to its position among the merged documents.
+func documentLabel(doc *APIDocument, index int) string {
+	if title := strings.TrimSpace(doc.Title); title != "" {
+		return title
+	}
+	return fmt.Sprintf("document %d", index+1)
+}
@@ -0,0 +1,307 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// =====================================================
+// Normalization
+// =====================================================
+
+// Normalize tidies doc in place so that it renders the same way however the
+// input was written: methods are uppercased, whitespace around paths is
+// trimmed, endpoints are sorted by path and then in the order of
+// canonicalMethods, and each endpoint's parameters are sorted by location and
+// name.
+func Normalize(doc *APIDocument) {
+	for i := range doc.Endpoints {
+		ep := &doc.Endpoints[i]
+		ep.Method = strings.ToUpper(strings.TrimSpace(ep.Method))
+		ep.Path = strings.TrimSpace(ep.Path)
+		params := ep.Parameters
+		sort.SliceStable(params, func(i, j int) bool {
+			a, b := params[i], params[j]
+			switch {
+			case a == nil || b == nil:
+				return b == nil && a != nil
+			case a.In != b.In:
+				return a.In < b.In
+			}
+			return a.Name < b.Name
+		})
+	}
+	sort.SliceStable(doc.Endpoints, func(i, j int) bool {
+		a, b := doc.Endpoints[i], doc.Endpoints[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return methodRank(a.Method) < methodRank(b.Method)
+	})
+}
+
+// NormalizePaths merges trailing-slash variants of a path, such as /pets/
+// next to /pets, onto the path without the slash. An endpoint whose method is
+// declared on both variants is kept once, from the canonical path, with a note
+// in its description. It returns the number of endpoints that were moved or
+// dropped. Paths declared only with a trailing slash are left alone.
+func NormalizePaths(doc *APIDocument) int {
+	paths := make(map[string]bool, len(doc.Endpoints))
+	declared := make(map[string]bool, len(doc.Endpoints))
+	for _, ep := range doc.Endpoints {
+		paths[ep.Path] = true
+		declared[endpointKey(ep.Method, ep.Path)] = true
+	}
+
+	merged := 0
+	aliases := make(map[string]string)
+	kept := doc.Endpoints[:0]
+	for _, ep := range doc.Endpoints {
+		canonical := strings.TrimSuffix(ep.Path, "/")
+		if canonical == ep.Path || canonical == "" || !paths[canonical] {
+			kept = append(kept, ep)
+			continue
+		}
+		merged++
+		if key := endpointKey(ep.Method, canonical); declared[key] {
+			aliases[key] = ep.Path
+			continue
+		}
+		ep.Path = canonical
+		kept = append(kept, ep)
+	}
+	doc.Endpoints = kept
+
+	for i := range doc.Endpoints {
+		ep := &doc.Endpoints[i]
+		alias, ok := aliases[endpointKey(ep.Method, ep.Path)]
+		if !ok {
+			continue
+		}
+		note := fmt.Sprintf("(Also declared as %s.)", alias)
+		if ep.Description == "" {
+			ep.Description = note
+		} else {
+			ep.Description += " " + note
+		}
+	}
+	return merged
+}
+
+// endpointKey identifies an endpoint by its method and path, e.g. "GET /pets".
+func endpointKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// DeduplicateSchemas hoists inline object schemas that occur more than once
+// with identical structure into doc.Components.Schemas, so the renderers
+// show them by name instead of repeating their fields. Run it after
+// ResolveReferences. Each hoisted schema is named after where it first
+// occurs, such as the property holding it ("Address") or the operation whose
+// body it is ("CreatePetRequest"), with a number added when the name is
+// taken. It returns the names of the hoisted schemas.
+func DeduplicateSchemas(doc *APIDocument) []string {
+	var hoisted []string
+	// Hoisting an outer schema leaves one copy of the schemas nested in it,
+	// so repeat with fresh counts until nothing is duplicated.
+	for {
+		counts := make(map[string]int)
+		walkInlineSchemas(doc, func(slot **Schema, hint string) bool {
+			counts[schemaKey(*slot)]++
+			return true
+		})
+
+		shared := make(map[string]*Schema)
+		walkInlineSchemas(doc, func(slot **Schema, hint string) bool {
+			key := schemaKey(*slot)
+			if counts[key] < 2 {
+				return true
+			}
+			if s, ok := shared[key]; ok {
+				*slot = s
+				return false
+			}
+			name := hoistSchema(doc, *slot, hint)
+			shared[key] = *slot
+			hoisted = append(hoisted, name)
+			return false
+		})
+		if len(shared) == 0 {
+			return hoisted
+		}
+	}
+}
+
+// hoistSchema adds s to doc's component schemas under a name derived from
+// hint and returns the name.
+func hoistSchema(doc *APIDocument, s *Schema, hint string) string {
+	if doc.Components == nil {
+		doc.Components = &Components{}
+	}
+	if doc.Components.Schemas == nil {
+		doc.Components.Schemas = make(map[string]*Schema)
+	}
+	base := schemaNameFromHint(hint)
+	name := base
+	for i := 2; doc.Components.Schemas[name] != nil; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	s.Name = name
+	doc.Components.Schemas[name] = s
+	return name
+}
+
+// schemaNameFromHint turns a hint such as "shipping_address" or
+// "createPet Request" into a schema name such as "ShippingAddress" or
+// "CreatePetRequest".
+func schemaNameFromHint(hint string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range hint {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() == 0 || unicode.IsDigit([]rune(sb.String())[0]) {
+		return "Inline" + sb.String()
+	}
+	return sb.String()
+}
+
+// walkInlineSchemas calls visit for every unnamed object schema reachable
+// from doc's endpoints and component schemas, with a hint for naming it.
+// visit returns whether to descend into the schema's own nested schemas.
+// Each schema is visited once however many places share it. Named schemas
+// are not visited themselves; those in doc.Components are descended into
+// once, as component roots.
+func walkInlineSchemas(doc *APIDocument, visit func(slot **Schema, hint string) bool) {
+	seen := make(map[*Schema]bool)
+	var walk func(slot **Schema, hint string)
+	walk = func(slot **Schema, hint string) {
+		s := *slot
+		if s == nil || s.Name != "" || seen[s] {
+			return
+		}
+		seen[s] = true
+		if len(s.Properties) > 0 && !visit(slot, hint) {
+			return
+		}
+		walkNestedSchemas(*slot, hint, walk)
+	}
+
+	for _, ep := range doc.Endpoints {
+		prefix := ep.OperationID
+		if prefix == "" {
+			prefix = strings.ToLower(ep.Method) + " " + ep.Path
+		}
+		for _, p := range ep.Parameters {
+			if p != nil {
+				walk(&p.Schema, p.Name)
+			}
+		}
+		if ep.RequestBody != nil {
+			for _, mediaType := range sortedMediaTypes(ep.RequestBody.Content) {
+				if mt := ep.RequestBody.Content[mediaType]; mt != nil {
+					walk(&mt.Schema, prefix+" Request")
+				}
+			}
+		}
+		for _, code := range sortedResponseCodes(ep.Responses) {
+			resp := ep.Responses[code]
+			if resp == nil {
+				continue
+			}
+			for _, mediaType := range sortedMediaTypes(resp.Content) {
+				if mt := resp.Content[mediaType]; mt != nil {
+					walk(&mt.Schema, prefix+" Response")
+				}
+			}
+		}
+	}
+	if doc.Components != nil {
+		for _, name := range sortedSchemaNames(doc.Components.Schemas) {
+			if s := doc.Components.Schemas[name]; s != nil {
+				walkNestedSchemas(s, name, walk)
+			}
+		}
+	}
+}
+
+// walkNestedSchemas calls walk for the properties, array items and
+// alternatives of s.
+func walkNestedSchemas(s *Schema, hint string, walk func(slot **Schema, hint string)) {
+	for _, name := range sortedSchemaNames(s.Properties) {
+		prop := s.Properties[name]
+		walk(&prop, name)
+		s.Properties[name] = prop
+	}
+	walk(&s.Items, hint+" Item")
+	for i := range s.OneOf {
+		walk(&s.OneOf[i], hint)
+	}
+	for i := range s.AnyOf {
+		walk(&s.AnyOf[i], hint)
+	}
+	for i := range s.AllOf {
+		walk(&s.AllOf[i], hint)
+	}
+}
+
+// schemaKey identifies the structure of s: two schemas with the same key
+// are interchangeable. Named schemas nested in s count by name only.
+func schemaKey(s *Schema) string {
+	data, err := yaml.Marshal(schemaKeyCopy(s, make(map[*Schema]bool)))
+	if err != nil {
+		return fmt.Sprintf("%p", s)
+	}
+	return string(data)
+}
+
+// schemaKeyCopy copies s for schemaKey, with named schemas replaced by a
+// $ref and any recursion cut off.
+func schemaKeyCopy(s *Schema, ancestors map[*Schema]bool) *Schema {
+	switch {
+	case s == nil:
+		return nil
+	case s.Name != "":
+		return &Schema{Ref: "#/components/schemas/" + s.Name}
+	case ancestors[s]:
+		return &Schema{Ref: "#recursive"}
+	}
+	ancestors[s] = true
+	defer delete(ancestors, s)
+
+	c := *s
+	c.Items = schemaKeyCopy(s.Items, ancestors)
+	if s.Properties != nil {
+		c.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			c.Properties[name] = schemaKeyCopy(prop, ancestors)
+		}
+	}
+	c.OneOf = schemaKeyCopies(s.OneOf, ancestors)
+	c.AnyOf = schemaKeyCopies(s.AnyOf, ancestors)
+	c.AllOf = schemaKeyCopies(s.AllOf, ancestors)
+	return &c
+}
+
+func schemaKeyCopies(schemas []*Schema, ancestors map[*Schema]bool) []*Schema {
+	if schemas == nil {
+		return nil
+	}
+	result := make([]*Schema, 0, len(schemas))
+	for _, s := range schemas {
+		result = append(result, schemaKeyCopy(s, ancestors))
+	}
+	return result
+}
@@ -2,10 +2,23 @@ package openapi
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v2"
 )
@@ -20,77 +33,416 @@ type APIDocument struct {
 	Version     string      `json:"version" yaml:"version"`
 	Description string      `json:"description,omitempty" yaml:"description,omitempty"`
 	Endpoints   []Endpoint  `json:"endpoints" yaml:"endpoints"`
-	Servers     []string    `json:"servers" yaml:"servers"`
-	Components  *Components `json:"components" yaml:"components"`
+	Servers     []Server    `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Components  *Components `json:"components,omitempty" yaml:"components,omitempty"`
+	// Deprecated marks the whole API version as sunset; ReplacedBy names the
+	// version to migrate to.
+	Deprecated bool   `json:"x-deprecated,omitempty" yaml:"x-deprecated,omitempty"`
+	ReplacedBy string `json:"x-replaced-by,omitempty" yaml:"x-replaced-by,omitempty"`
+	// TermsOfService is the URL of the API's terms of service.
+	TermsOfService string `json:"termsOfService,omitempty" yaml:"termsOfService,omitempty"`
+	// Consumes and Produces are the default request and response media
+	// types, inherited by endpoints that do not declare their own.
+	Consumes []string `json:"consumes,omitempty" yaml:"consumes,omitempty"`
+	Produces []string `json:"produces,omitempty" yaml:"produces,omitempty"`
+
+	// SourcePath is the file the document was loaded from, if any. Refs into
+	// other files are resolved relative to it. For a spec loaded from a zip
+	// bundle it is the root spec's path inside the archive.
+	SourcePath string `json:"-" yaml:"-"`
+
+	// sourceFS is the zip bundle the document was loaded from, if any; refs
+	// into other files are read from it.
+	sourceFS fs.FS
+
+	// pathRefs are the path items that were only a $ref when the spec was
+	// converted; ResolveReferences splices in their operations.
+	pathRefs []pathRef
+}
+
+// pathRef is a path item given as a $ref, e.g. to
+// "#/components/pathItems/Pets" or "./paths/pets.yaml". security is the
+// document-wide security requirement its operations inherit.
+type pathRef struct {
+	path     string
+	ref      string
+	security []SecurityRequirement
+}
+
+// Server is a base URL the API is served from.
+type Server struct {
+	URL         string `json:"url" yaml:"url"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// UnmarshalJSON accepts either a server object or a bare URL string, the
+// form servers were listed in before descriptions were supported.
+func (s *Server) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*s = Server{URL: url}
+		return nil
+	}
+	type plain Server
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// UnmarshalYAML accepts either a server object or a bare URL string.
+func (s *Server) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var url string
+	if err := unmarshal(&url); err == nil {
+		*s = Server{URL: url}
+		return nil
+	}
+	type plain Server
+	return unmarshal((*plain)(s))
 }
 
 // Endpoint represents a simplified API endpoint.
 type Endpoint struct {
 	Path        string               `json:"path" yaml:"path"`
 	Method      string               `json:"method" yaml:"method"`
-	Summary     string               `json:"summary" yaml:"summary"`
+	Summary     string               `json:"summary,omitempty" yaml:"summary,omitempty"`
+	OperationID string               `json:"operationId,omitempty" yaml:"operationId,omitempty"`
 	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
-	Parameters  []*Parameter         `json:"parameters" yaml:"parameters"`
-	RequestBody *RequestBody         `json:"requestBody" yaml:"requestBody"`
-	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+	Parameters  []*Parameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Tags        []string             `json:"tags,omitempty" yaml:"tags,omitempty"`
+	CodeSamples []CodeSample         `json:"x-codeSamples,omitempty" yaml:"x-codeSamples,omitempty"`
+	// Idempotent overrides the idempotency implied by the HTTP method, e.g.
+	// for a POST that honors idempotency keys.
+	Idempotent *bool `json:"x-idempotent,omitempty" yaml:"x-idempotent,omitempty"`
+	// Servers overrides the document-level servers for this endpoint only.
+	Servers []Server `json:"servers,omitempty" yaml:"servers,omitempty"`
+	// Since is the API version that introduced the endpoint, e.g. "2.3".
+	Since string `json:"x-since,omitempty" yaml:"x-since,omitempty"`
+	// Source names the document an endpoint came from when MergeDocuments
+	// kept conflicting declarations of it.
+	Source string `json:"x-source,omitempty" yaml:"x-source,omitempty"`
+	// Consumes and Produces override the document's default media types.
+	Consumes []string `json:"consumes,omitempty" yaml:"consumes,omitempty"`
+	Produces []string `json:"produces,omitempty" yaml:"produces,omitempty"`
+	// Deprecated marks an operation that should no longer be called.
+	Deprecated bool `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// Security names the security schemes the endpoint accepts, one
+	// alternative per entry. An alternative that needs several schemes at
+	// once joins their names with " + ", and an empty entry means the
+	// endpoint can also be called anonymously.
+	Security []string `json:"security,omitempty" yaml:"security,omitempty"`
+	// SecurityScopes lists, parallel to Security, the OAuth2 scopes each
+	// alternative requests from its schemes. An entry is nil when that
+	// alternative requests none, and the list is empty when none does.
+	SecurityScopes []map[string][]string `json:"securityScopes,omitempty" yaml:"securityScopes,omitempty"`
+}
+
+// CodeSample is a hand-written usage example from the Redoc-style
+// x-codeSamples extension.
+type CodeSample struct {
+	Lang   string `json:"lang" yaml:"lang"`
+	Label  string `json:"label,omitempty" yaml:"label,omitempty"`
+	Source string `json:"source" yaml:"source"`
 }
 
 // Parameter represents a simplified parameter.
 type Parameter struct {
-	Name        string  `json:"name" yaml:"name"`
-	In          string  `json:"in" yaml:"in"`
-	Required    bool    `json:"required" yaml:"required"`
+	Name     string `json:"name" yaml:"name"`
+	In       string `json:"in" yaml:"in"`
+	Required bool   `json:"required,omitempty" yaml:"required,omitempty"`
 	// New field: capture the type directly if present.
 	Type        string  `json:"type,omitempty" yaml:"type,omitempty"`
-	Schema      *Schema `json:"schema" yaml:"schema"`
+	Format      string  `json:"format,omitempty" yaml:"format,omitempty"`
+	Schema      *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
 	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
 	Ref         string  `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	// Items describes array elements for Swagger 2.0 non-body parameters.
+	Items      *Schema `json:"items,omitempty" yaml:"items,omitempty"`
+	Deprecated bool    `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// ReplacedBy names the parameter to use instead of a deprecated one.
+	ReplacedBy string      `json:"x-replaced-by,omitempty" yaml:"x-replaced-by,omitempty"`
+	Default    interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	// Enum and Example carry Swagger 2.0 parameter-level values; OpenAPI 3
+	// keeps them on the schema, except for example.
+	Enum    []interface{} `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Example interface{}   `json:"example,omitempty" yaml:"example,omitempty"`
+	// Style and Explode control how OpenAPI 3 serializes array and object
+	// values; CollectionFormat is the Swagger 2.0 equivalent for arrays.
+	Style            string `json:"style,omitempty" yaml:"style,omitempty"`
+	Explode          *bool  `json:"explode,omitempty" yaml:"explode,omitempty"`
+	CollectionFormat string `json:"collectionFormat,omitempty" yaml:"collectionFormat,omitempty"`
+	// Constraints carries Swagger 2.0 parameter-level validation; OpenAPI 3
+	// keeps it on the schema.
+	Constraints `yaml:",inline"`
+}
+
+// Constraints are the validation keywords that limit the values a schema or
+// parameter accepts.
+type Constraints struct {
+	Minimum   *float64 `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	MinLength *int     `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
 }
 
 // RequestBody represents a simplified request body.
 type RequestBody struct {
-	Description string                `json:"description" yaml:"description"`
-	Content     map[string]*MediaType `json:"content" yaml:"content"`
+	Description string                `json:"description,omitempty" yaml:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
 	Ref         string                `json:"$ref,omitempty" yaml:"$ref,omitempty"`
 }
 
 // Response represents a simplified response.
 type Response struct {
-	Description string                `json:"description" yaml:"description"`
-	Content     map[string]*MediaType `json:"content" yaml:"content"`
+	Description string                `json:"description,omitempty" yaml:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
 	Ref         string                `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	// Schema is the Swagger 2.0 response body schema. Conversion moves it
+	// into Content as application/json.
+	Schema *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+	// Examples are the Swagger 2.0 example bodies by media type. Conversion
+	// moves them into Content as each media type's Example.
+	Examples map[string]interface{} `json:"examples,omitempty" yaml:"examples,omitempty"`
+	// Headers are the response headers by name, such as X-Rate-Limit or
+	// Location. Like parameters, they are typed by Schema in OpenAPI 3 and
+	// by Type and Format in Swagger 2.0.
+	Headers map[string]*Parameter `json:"headers,omitempty" yaml:"headers,omitempty"`
 }
 
-// MediaType holds the media type object (only schema is used here).
+// MediaType holds the media type object.
 type MediaType struct {
-	Schema *Schema `json:"schema" yaml:"schema"`
+	Schema   *Schema             `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Examples map[string]*Example `json:"examples,omitempty" yaml:"examples,omitempty"`
+	// Example is a single example body, rendered in full under EXAMPLE:.
+	Example interface{} `json:"example,omitempty" yaml:"example,omitempty"`
+}
+
+// Example is a named example payload. Summary and Description explain the
+// scenario the example illustrates.
+type Example struct {
+	Summary       string      `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description   string      `json:"description,omitempty" yaml:"description,omitempty"`
+	Value         interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+	ExternalValue string      `json:"externalValue,omitempty" yaml:"externalValue,omitempty"`
+	Ref           string      `json:"$ref,omitempty" yaml:"$ref,omitempty"`
 }
 
 // Schema represents a simplified schema.
 type Schema struct {
-	Type string `json:"type" yaml:"type"`
-	Ref  string `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type          SchemaType         `json:"type,omitempty" yaml:"type,omitempty"`
+	Ref           string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Properties    map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Items         *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Format        string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Required      []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	OneOf         []*Schema          `json:"oneOf,omitempty" yaml:"oneOf,omitempty"`
+	Discriminator *Discriminator     `json:"discriminator,omitempty" yaml:"discriminator,omitempty"`
+	Example       interface{}        `json:"example,omitempty" yaml:"example,omitempty"`
+	Enum          []interface{}      `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Const         interface{}        `json:"const,omitempty" yaml:"const,omitempty"`
+	Default       interface{}        `json:"default,omitempty" yaml:"default,omitempty"`
+	ReadOnly      bool               `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+	WriteOnly     bool               `json:"writeOnly,omitempty" yaml:"writeOnly,omitempty"`
+	Deprecated    bool               `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// ReplacedBy names the field to use instead of a deprecated one.
+	ReplacedBy string `json:"x-replaced-by,omitempty" yaml:"x-replaced-by,omitempty"`
+	// Since is the API version that introduced the field, e.g. "2.3".
+	Since string `json:"x-since,omitempty" yaml:"x-since,omitempty"`
+	// AnyOf lists alternatives of which one or more may match, unlike
+	// OneOf, where exactly one does.
+	AnyOf []*Schema `json:"anyOf,omitempty" yaml:"anyOf,omitempty"`
+	// AllOf lists schemas whose properties are combined into this one.
+	// ResolveReferences merges them into Properties and clears the list.
+	AllOf []*Schema `json:"allOf,omitempty" yaml:"allOf,omitempty"`
+	// Nullable is the OpenAPI 3.0 flag for a value that may be null; 3.1
+	// lists "null" in Type instead.
+	Nullable bool `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	// XML names the element or attribute the schema is written as in XML
+	// bodies.
+	XML *XML `json:"xml,omitempty" yaml:"xml,omitempty"`
+	// Constraints limits the values the schema accepts.
+	Constraints `yaml:",inline"`
+
+	// Name is the component name this schema was registered under. It is set
+	// by ResolveReferences so renderers can still refer to a resolved schema
+	// by name.
+	Name string `json:"-" yaml:"-"`
+}
+
+// XML describes how a schema is written in XML: its element name, namespace
+// and prefix, whether a property is an attribute rather than an element, and
+// whether array items are wrapped in an enclosing element.
+type XML struct {
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Prefix    string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Attribute bool   `json:"attribute,omitempty" yaml:"attribute,omitempty"`
+	Wrapped   bool   `json:"wrapped,omitempty" yaml:"wrapped,omitempty"`
+}
+
+// nullable reports whether s accepts null, by the OpenAPI 3.0 nullable flag
+// or a 3.1 type list that includes "null".
+func (s *Schema) nullable() bool {
+	return s.Nullable || s.Type.Nullable()
+}
+
+// SchemaType is the type of a schema, e.g. "string". OpenAPI 3.1 also allows
+// a list of types such as [string, "null"]; it is stored joined with "|",
+// e.g. "string|null", and written back as a list.
+type SchemaType string
+
+// nullType is the type OpenAPI 3.1 uses to mark a nullable schema.
+const nullType = "null"
+
+// UnmarshalYAML accepts a type name or a list of them.
+func (t *SchemaType) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var types []string
+	if err := unmarshal(&types); err == nil {
+		*t = SchemaType(strings.Join(types, "|"))
+		return nil
+	}
+	var single string
+	if err := unmarshal(&single); err != nil {
+		return err
+	}
+	*t = SchemaType(single)
+	return nil
+}
+
+// UnmarshalJSON accepts a type name or a list of them.
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var types []string
+	if err := json.Unmarshal(data, &types); err == nil {
+		*t = SchemaType(strings.Join(types, "|"))
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*t = SchemaType(single)
+	return nil
+}
+
+// MarshalYAML writes a union back as a list.
+func (t SchemaType) MarshalYAML() (interface{}, error) {
+	if types := t.Types(); len(types) > 1 {
+		return types, nil
+	}
+	return string(t), nil
+}
+
+// MarshalJSON writes a union back as a list.
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if types := t.Types(); len(types) > 1 {
+		return json.Marshal(types)
+	}
+	return json.Marshal(string(t))
+}
+
+// Types returns the individual types of t, e.g. ["string", "null"].
+func (t SchemaType) Types() []string {
+	if t == "" {
+		return nil
+	}
+	return strings.Split(string(t), "|")
+}
+
+// Base returns the type with "null" left out of a union, e.g. "string" for
+// "string|null", so that a nullable array is still rendered as an array.
+func (t SchemaType) Base() string {
+	var types []string
+	for _, typ := range t.Types() {
+		if typ != nullType {
+			types = append(types, typ)
+		}
+	}
+	return strings.Join(types, "|")
+}
+
+// Nullable reports whether t lists "null" among its types.
+func (t SchemaType) Nullable() bool {
+	for _, typ := range t.Types() {
+		if typ == nullType {
+			return true
+		}
+	}
+	return false
+}
+
+// Discriminator names the property that selects between oneOf variants.
+type Discriminator struct {
+	PropertyName string            `json:"propertyName" yaml:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty" yaml:"mapping,omitempty"`
 }
 
 // Components holds reusable objects.
 type Components struct {
-	Schemas       map[string]*Schema      `json:"schemas" yaml:"schemas"`
-	Parameters    map[string]*Parameter   `json:"parameters" yaml:"parameters"`
-	RequestBodies map[string]*RequestBody `json:"requestBodies" yaml:"requestBodies"`
-	Responses     map[string]*Response    `json:"responses" yaml:"responses"`
+	Schemas       map[string]*Schema      `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	Parameters    map[string]*Parameter   `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBodies map[string]*RequestBody `json:"requestBodies,omitempty" yaml:"requestBodies,omitempty"`
+	Responses     map[string]*Response    `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Examples      map[string]*Example     `json:"examples,omitempty" yaml:"examples,omitempty"`
+	// SecuritySchemes holds the authentication schemes endpoints refer to
+	// by name, from OpenAPI 3 securitySchemes or Swagger 2.0
+	// securityDefinitions.
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
+	// Headers holds reusable response headers that responses refer to with
+	// "#/components/headers/Name".
+	Headers map[string]*Parameter `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// PathItems holds reusable path items that paths refer to with
+	// "#/components/pathItems/Name".
+	PathItems map[string]*PathItem `json:"pathItems,omitempty" yaml:"pathItems,omitempty"`
 }
 
+// SecurityScheme describes one way of authenticating: an API key, HTTP
+// basic or bearer auth, OAuth2 or OpenID Connect.
+type SecurityScheme struct {
+	// Type is apiKey, http, oauth2 or openIdConnect, or basic in Swagger 2.0.
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Name and In locate an apiKey, e.g. the X-API-Key header.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	In   string `json:"in,omitempty" yaml:"in,omitempty"`
+	// Scheme and BearerFormat qualify http auth, e.g. bearer and JWT.
+	Scheme       string `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty" yaml:"bearerFormat,omitempty"`
+	// Flow, AuthorizationURL, TokenURL and Scopes describe a Swagger 2.0
+	// oauth2 scheme; Scopes maps each scope it offers to its description.
+	Flow             string            `json:"flow,omitempty" yaml:"flow,omitempty"`
+	AuthorizationURL string            `json:"authorizationUrl,omitempty" yaml:"authorizationUrl,omitempty"`
+	TokenURL         string            `json:"tokenUrl,omitempty" yaml:"tokenUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+}
+
+// SecurityRequirement maps the names of the schemes that must all be
+// satisfied together to the OAuth2 scopes they need. A list of requirements
+// is satisfied by any one of them.
+type SecurityRequirement map[string][]string
+
 // =====================================================
 // Swagger 2.0 Structures
 // =====================================================
 
 // SwaggerSpec represents a Swagger 2.0 specification.
 type SwaggerSpec struct {
-	Swagger  string              `yaml:"swagger" json:"swagger"`
-	Info     SwaggerInfo         `yaml:"info" json:"info"`
-	BasePath string              `yaml:"basePath" json:"basePath"`
-	Paths    map[string]PathItem `yaml:"paths" json:"paths"`
-	// Additional fields (host, schemes, definitions, etc.) can be added as needed.
+	Swagger    string               `yaml:"swagger" json:"swagger"`
+	Info       SwaggerInfo          `yaml:"info" json:"info"`
+	Host       string               `yaml:"host" json:"host"`
+	BasePath   string               `yaml:"basePath" json:"basePath"`
+	Schemes    []string             `yaml:"schemes" json:"schemes"`
+	Paths      map[string]PathItem  `yaml:"paths" json:"paths"`
+	Parameters map[string]Parameter `yaml:"parameters" json:"parameters"`
+	Responses  map[string]Response  `yaml:"responses" json:"responses"`
+	Consumes   []string             `yaml:"consumes" json:"consumes"`
+	Produces   []string             `yaml:"produces" json:"produces"`
+	// Definitions holds the reusable schemas referenced as #/definitions/Name.
+	Definitions map[string]*Schema `yaml:"definitions" json:"definitions"`
+	// SecurityDefinitions and Security are the authentication schemes and
+	// the requirement applied to operations that declare none of their own.
+	SecurityDefinitions map[string]*SecurityScheme `yaml:"securityDefinitions" json:"securityDefinitions"`
+	Security            []SecurityRequirement      `yaml:"security" json:"security"`
+	// Additional fields can be added as needed.
 }
 
 // SwaggerInfo holds API info for Swagger.
@@ -98,17 +450,27 @@ type SwaggerInfo struct {
 	Title       string `yaml:"title" json:"title"`
 	Description string `yaml:"description" json:"description"`
 	Version     string `yaml:"version" json:"version"`
+	Deprecated  bool   `yaml:"x-deprecated" json:"x-deprecated"`
+	ReplacedBy  string `yaml:"x-replaced-by" json:"x-replaced-by"`
+	// TermsOfService is the URL of the API's terms of service.
+	TermsOfService string `yaml:"termsOfService" json:"termsOfService"`
 }
 
 // PathItem represents the available operations for a single path.
 type PathItem struct {
-	Get     *Operation `yaml:"get" json:"get"`
-	Put     *Operation `yaml:"put" json:"put"`
-	Post    *Operation `yaml:"post" json:"post"`
-	Delete  *Operation `yaml:"delete" json:"delete"`
-	Options *Operation `yaml:"options" json:"options"`
-	Head    *Operation `yaml:"head" json:"head"`
-	Patch   *Operation `yaml:"patch" json:"patch"`
+	Get     *Operation `yaml:"get,omitempty" json:"get,omitempty"`
+	Put     *Operation `yaml:"put,omitempty" json:"put,omitempty"`
+	Post    *Operation `yaml:"post,omitempty" json:"post,omitempty"`
+	Delete  *Operation `yaml:"delete,omitempty" json:"delete,omitempty"`
+	Options *Operation `yaml:"options,omitempty" json:"options,omitempty"`
+	Head    *Operation `yaml:"head,omitempty" json:"head,omitempty"`
+	Patch   *Operation `yaml:"patch,omitempty" json:"patch,omitempty"`
+	// Parameters apply to every operation of the path, unless an operation
+	// declares a parameter with the same name and location.
+	Parameters []Parameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	// Ref points at a path item defined elsewhere, in components.pathItems
+	// or another file.
+	Ref string `yaml:"$ref,omitempty" json:"$ref,omitempty"`
 }
 
 // Operation represents a Swagger operation.
@@ -118,24 +480,171 @@ type Operation struct {
 	OperationID string              `yaml:"operationId" json:"operationId"`
 	Parameters  []Parameter         `yaml:"parameters" json:"parameters"`
 	Responses   map[string]Response `yaml:"responses" json:"responses"`
+	Tags        []string            `yaml:"tags" json:"tags"`
+	CodeSamples []CodeSample        `yaml:"x-codeSamples" json:"x-codeSamples"`
+	Idempotent  *bool               `yaml:"x-idempotent" json:"x-idempotent"`
+	Since       string              `yaml:"x-since" json:"x-since"`
+	Consumes    []string            `yaml:"consumes" json:"consumes"`
+	Produces    []string            `yaml:"produces" json:"produces"`
+	Deprecated  bool                `yaml:"deprecated" json:"deprecated"`
+	// RequestBody and Servers are only set by OpenAPI 3 operations.
+	RequestBody *RequestBody `yaml:"requestBody" json:"requestBody"`
+	Servers     []Server     `yaml:"servers" json:"servers"`
+	// Security overrides the global requirement when set; an empty list
+	// means the operation needs no authentication.
+	Security *[]SecurityRequirement `yaml:"security" json:"security"`
+}
+
+// =====================================================
+// OpenAPI 3 Structures
+// =====================================================
+
+// OpenAPI3Spec represents an OpenAPI 3.0 or 3.1 specification. Its paths
+// share PathItem and Operation with Swagger 2.0.
+type OpenAPI3Spec struct {
+	OpenAPI    string              `yaml:"openapi" json:"openapi"`
+	Info       SwaggerInfo         `yaml:"info" json:"info"`
+	Servers    []Server            `yaml:"servers" json:"servers"`
+	Paths      map[string]PathItem `yaml:"paths" json:"paths"`
+	Components *Components         `yaml:"components" json:"components"`
+	// Security applies to operations that declare no security of their own.
+	Security []SecurityRequirement `yaml:"security" json:"security"`
 }
 
 // =====================================================
 // Parsing and Conversion Functions
 // =====================================================
 
+// LoadOptions customizes LoadAPISpecWithOptions.
+type LoadOptions struct {
+	// AssumeJSON parses the spec with encoding/json only, skipping the slower
+	// YAML detection pass. Input that is not JSON is rejected.
+	AssumeJSON bool
+	// AllowJSONComments accepts JSON specs containing // and /* */ comments
+	// (JSONC). It has no effect on YAML input.
+	AllowJSONComments bool
+	// HTTPTimeout bounds fetching a spec given as an http:// or https:// URL.
+	// Zero means DefaultHTTPTimeout.
+	HTTPTimeout time.Duration
+	// BundleRoot is the path of the root spec inside a .zip bundle. It
+	// defaults to the shallowest entry named as in DefaultBundleRoots.
+	BundleRoot string
+
+	// assumeYAML decodes a body starting with "{" as a YAML flow mapping
+	// rather than JSON; set for remote specs served with a YAML Content-Type.
+	assumeYAML bool
+}
+
 // LoadAPISpec reads a YAML or JSON file and unmarshals it into an APIDocument.
-// It supports both the simplified API spec format and Swagger 2.0.
+// It supports the simplified API spec format, Swagger 2.0 and OpenAPI 3.x.
+// A path starting with http:// or https:// is fetched over HTTP. A .zip
+// file is read as a bundle of the root spec and the files it refers to.
 func LoadAPISpec(path string) (*APIDocument, error) {
-	data, err := ioutil.ReadFile(path)
+	return LoadAPISpecWithOptions(path, LoadOptions{})
+}
+
+// LoadAPISpecWithOptions is LoadAPISpec with a custom configuration.
+func LoadAPISpecWithOptions(path string, opts LoadOptions) (*APIDocument, error) {
+	if isURL(path) {
+		return loadRemoteSpec(path, opts)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return loadBundleSpec(path, opts)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	doc, err := loadSpecReader(f, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	doc.SourcePath = path
+	return doc, nil
+}
+
+// LoadAPISpecReader is LoadAPISpec for a spec read from r, such as stdin.
+// The format is detected from the content alone. Relative refs into other
+// files resolve against the working directory.
+func LoadAPISpecReader(r io.Reader) (*APIDocument, error) {
+	return LoadAPISpecReaderWithOptions(r, LoadOptions{})
+}
+
+// LoadAPISpecReaderWithOptions is LoadAPISpecReader with a custom
+// configuration.
+func LoadAPISpecReaderWithOptions(r io.Reader, opts LoadOptions) (*APIDocument, error) {
+	return loadSpecReader(r, "", opts)
+}
+
+// loadSpecReader reads, decompresses and parses a spec. name, when known,
+// helps detect gzip files and labels errors.
+func loadSpecReader(r io.Reader, name string, opts LoadOptions) (*APIDocument, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = "spec"
+	}
+	data, err = decompressSpec(data, name)
 	if err != nil {
 		return nil, err
 	}
+	return parseAPISpec(data, opts)
+}
+
+// gzipMagic is the two-byte header every gzip stream starts with.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompressSpec returns data gunzipped when it is a gzip stream, detected
+// by a .gz extension on name or by the gzip magic bytes, and unchanged
+// otherwise.
+func decompressSpec(data []byte, name string) ([]byte, error) {
+	if !strings.HasSuffix(strings.ToLower(name), ".gz") && !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s: corrupt gzip stream: %w", name, err)
+	}
+	defer zr.Close()
+	out, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s: corrupt gzip stream: %w", name, err)
+	}
+	return out, nil
+}
 
+// parseAPISpec detects the format of a spec held in memory and converts it
+// into an APIDocument.
+func parseAPISpec(data []byte, opts LoadOptions) (*APIDocument, error) {
 	trimmed := bytes.TrimSpace(data)
 	if len(trimmed) == 0 {
 		return &APIDocument{}, nil
 	}
+	if opts.AllowJSONComments && (trimmed[0] == '{' || trimmed[0] == '/') {
+		data = stripJSONComments(data)
+		trimmed = bytes.TrimSpace(data)
+		if len(trimmed) == 0 {
+			return &APIDocument{}, nil
+		}
+	}
+	if opts.AssumeJSON {
+		return parseJSONSpec(trimmed)
+	}
+	isJSON := trimmed[0] == '{' && !opts.assumeYAML
+
+	var err error
+	if !isJSON {
+		if n, err := countYAMLDocuments(data); err != nil {
+			return nil, err
+		} else if n > 1 {
+			return nil, fmt.Errorf("spec contains %d YAML documents separated by ---, but only "+
+				"one is supported; join them into one document or load them separately and "+
+				"combine them with MergeDocuments", n)
+		}
+	}
 
 	// Unmarshal into a generic map to check for a "swagger" key.
 	var raw map[string]interface{}
@@ -146,7 +655,7 @@ func LoadAPISpec(path string) (*APIDocument, error) {
 	if _, isSwagger := raw["swagger"]; isSwagger {
 		// Unmarshal into SwaggerSpec.
 		var swaggerSpec SwaggerSpec
-		if trimmed[0] == '{' {
+		if isJSON {
 			err = json.Unmarshal(data, &swaggerSpec)
 		} else {
 			err = yaml.Unmarshal(data, &swaggerSpec)
@@ -159,9 +668,23 @@ func LoadAPISpec(path string) (*APIDocument, error) {
 		return &doc, nil
 	}
 
+	if _, isOpenAPI3 := raw["openapi"]; isOpenAPI3 {
+		var spec OpenAPI3Spec
+		if isJSON {
+			err = json.Unmarshal(data, &spec)
+		} else {
+			err = yaml.Unmarshal(data, &spec)
+		}
+		if err != nil {
+			return nil, err
+		}
+		doc := convertOpenAPI3ToAPIDocument(spec)
+		return &doc, nil
+	}
+
 	// Otherwise, assume it's already in the simplified APIDocument format.
 	var doc APIDocument
-	if trimmed[0] == '{' {
+	if isJSON {
 		err = json.Unmarshal(data, &doc)
 	} else {
 		err = yaml.Unmarshal(data, &doc)
@@ -172,62 +695,321 @@ func LoadAPISpec(path string) (*APIDocument, error) {
 	return &doc, nil
 }
 
+// countYAMLDocuments returns the number of non-empty documents in a YAML
+// stream. yaml.Unmarshal reads only the first, silently dropping the rest.
+func countYAMLDocuments(data []byte) (int, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	count := 0
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if doc != nil {
+			count++
+		}
+	}
+}
+
+// parseJSONSpec is the encoding/json-only path behind LoadOptions.AssumeJSON.
+// The top-level keys are peeked as raw messages to detect the format without
+// decoding the whole document twice into generic values.
+func parseJSONSpec(data []byte) (*APIDocument, error) {
+	if data[0] != '{' {
+		return nil, fmt.Errorf("spec is not a JSON object (AssumeJSON is set; YAML input is not supported)")
+	}
+	var peek map[string]json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&peek); err != nil {
+		return nil, fmt.Errorf("parsing JSON spec: %w", err)
+	}
+
+	if _, isSwagger := peek["swagger"]; isSwagger {
+		var swaggerSpec SwaggerSpec
+		if err := json.NewDecoder(bytes.NewReader(data)).Decode(&swaggerSpec); err != nil {
+			return nil, fmt.Errorf("parsing Swagger spec: %w", err)
+		}
+		doc := convertSwaggerToAPIDocument(swaggerSpec)
+		return &doc, nil
+	}
+
+	if _, isOpenAPI3 := peek["openapi"]; isOpenAPI3 {
+		var spec OpenAPI3Spec
+		if err := json.NewDecoder(bytes.NewReader(data)).Decode(&spec); err != nil {
+			return nil, fmt.Errorf("parsing OpenAPI 3 spec: %w", err)
+		}
+		doc := convertOpenAPI3ToAPIDocument(spec)
+		return &doc, nil
+	}
+
+	var doc APIDocument
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing JSON spec: %w", err)
+	}
+	return &doc, nil
+}
+
 // convertSwaggerToAPIDocument converts a SwaggerSpec into our simplified APIDocument.
 func convertSwaggerToAPIDocument(sw SwaggerSpec) APIDocument {
 	doc := APIDocument{
 		Title:       sw.Info.Title,
 		Version:     sw.Info.Version,
 		Description: sw.Info.Description,
+		Deprecated:  sw.Info.Deprecated,
+		ReplacedBy:  sw.Info.ReplacedBy,
 		Endpoints:   []Endpoint{},
-		Servers:     []string{}, // Swagger 2.0 doesn't have a "servers" array.
+		Servers:     swaggerServers(sw),
+
+		TermsOfService: sw.Info.TermsOfService,
+		Consumes:       sw.Consumes,
+		Produces:       sw.Produces,
 	}
 
-	for path, item := range sw.Paths {
-		// For each HTTP method in the PathItem, create an Endpoint.
-		if item.Get != nil {
-			ep := createEndpointFromOperation(path, "GET", *item.Get)
-			doc.Endpoints = append(doc.Endpoints, ep)
+	addPaths(&doc, inlineBodyParameterRefs(sw.Paths, sw.Parameters), sw.Security)
+
+	// Top-level definitions, parameters and responses become components so
+	// that #/definitions/..., #/parameters/... and #/responses/... refs
+	// resolve. Security definitions join them as security schemes.
+	if len(sw.Definitions) > 0 || len(sw.Parameters) > 0 || len(sw.Responses) > 0 || len(sw.SecurityDefinitions) > 0 {
+		doc.Components = &Components{
+			Schemas:         sw.Definitions,
+			Parameters:      make(map[string]*Parameter, len(sw.Parameters)),
+			Responses:       convertResponses(sw.Responses),
+			SecuritySchemes: sw.SecurityDefinitions,
 		}
-		if item.Post != nil {
-			ep := createEndpointFromOperation(path, "POST", *item.Post)
-			doc.Endpoints = append(doc.Endpoints, ep)
+		for name, p := range sw.Parameters {
+			paramCopy := p
+			doc.Components.Parameters[name] = &paramCopy
 		}
-		if item.Put != nil {
-			ep := createEndpointFromOperation(path, "PUT", *item.Put)
-			doc.Endpoints = append(doc.Endpoints, ep)
+	}
+
+	return doc
+}
+
+// inlineBodyParameterRefs replaces refs to shared "body" and "formData"
+// parameters, such as #/parameters/PetBody, with the parameters themselves.
+// Those parameters become the request body during conversion, which runs
+// before ResolveReferences, so they cannot wait for it like other refs.
+// Operations are copied rather than changed in place.
+func inlineBodyParameterRefs(paths map[string]PathItem, shared map[string]Parameter) map[string]PathItem {
+	if len(shared) == 0 {
+		return paths
+	}
+	inline := func(params []Parameter) []Parameter {
+		var result []Parameter
+		for i, p := range params {
+			name, local := strings.CutPrefix(p.Ref, swaggerRefPrefixes["parameters"])
+			target, ok := shared[name]
+			if !local || !ok || (target.In != "body" && target.In != "formData") {
+				continue
+			}
+			if result == nil {
+				result = append([]Parameter{}, params...)
+			}
+			result[i] = target
 		}
-		if item.Delete != nil {
-			ep := createEndpointFromOperation(path, "DELETE", *item.Delete)
-			doc.Endpoints = append(doc.Endpoints, ep)
+		if result == nil {
+			return params
 		}
-		if item.Patch != nil {
-			ep := createEndpointFromOperation(path, "PATCH", *item.Patch)
-			doc.Endpoints = append(doc.Endpoints, ep)
+		return result
+	}
+	result := make(map[string]PathItem, len(paths))
+	for path, item := range paths {
+		item.Parameters = inline(item.Parameters)
+		for _, op := range []**Operation{&item.Get, &item.Put, &item.Post, &item.Delete, &item.Options, &item.Head, &item.Patch} {
+			if *op != nil {
+				opCopy := **op
+				opCopy.Parameters = inline(opCopy.Parameters)
+				*op = &opCopy
+			}
 		}
-		if item.Head != nil {
-			ep := createEndpointFromOperation(path, "HEAD", *item.Head)
-			doc.Endpoints = append(doc.Endpoints, ep)
+		result[path] = item
+	}
+	return result
+}
+
+// swaggerServers builds server URLs such as https://api.example.com/v1 from a
+// Swagger 2.0 host, basePath and schemes, one per scheme. Schemes default to
+// https; without a host there is no base URL to build.
+func swaggerServers(sw SwaggerSpec) []Server {
+	servers := []Server{}
+	if sw.Host == "" {
+		return servers
+	}
+	schemes := sw.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	basePath := strings.TrimRight(sw.BasePath, "/")
+	if basePath != "" && !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	for _, scheme := range schemes {
+		servers = append(servers, Server{URL: fmt.Sprintf("%s://%s%s", scheme, sw.Host, basePath)})
+	}
+	return servers
+}
+
+// convertOpenAPI3ToAPIDocument converts an OpenAPI 3.x spec into our
+// simplified APIDocument.
+func convertOpenAPI3ToAPIDocument(spec OpenAPI3Spec) APIDocument {
+	doc := APIDocument{
+		Title:       spec.Info.Title,
+		Version:     spec.Info.Version,
+		Description: spec.Info.Description,
+		Deprecated:  spec.Info.Deprecated,
+		ReplacedBy:  spec.Info.ReplacedBy,
+		Endpoints:   []Endpoint{},
+		Servers:     spec.Servers,
+		Components:  spec.Components,
+
+		TermsOfService: spec.Info.TermsOfService,
+	}
+	addPaths(&doc, spec.Paths, spec.Security)
+	return doc
+}
+
+// sortedPaths returns the keys of paths in alphabetical order, so converted
+// documents list their endpoints the same way on every run.
+func sortedPaths(paths map[string]PathItem) []string {
+	keys := make([]string, 0, len(paths))
+	for path := range paths {
+		keys = append(keys, path)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addPaths appends the endpoints of paths to doc in path order. A path item
+// that is a $ref is recorded for ResolveReferences to splice in later.
+func addPaths(doc *APIDocument, paths map[string]PathItem, security []SecurityRequirement) {
+	for _, path := range sortedPaths(paths) {
+		item := paths[path]
+		if item.Ref != "" {
+			doc.pathRefs = append(doc.pathRefs, pathRef{path: path, ref: item.Ref, security: security})
 		}
-		if item.Options != nil {
-			ep := createEndpointFromOperation(path, "OPTIONS", *item.Options)
-			doc.Endpoints = append(doc.Endpoints, ep)
+		doc.Endpoints = appendPathEndpoints(doc.Endpoints, path, item, security)
+	}
+}
+
+// appendPathEndpoints appends an Endpoint for each HTTP method declared in
+// the PathItem, in the order of canonicalMethods. security is the
+// document-wide security requirement.
+func appendPathEndpoints(endpoints []Endpoint, path string, item PathItem, security []SecurityRequirement) []Endpoint {
+	add := func(method string, op *Operation) {
+		if op != nil {
+			endpoints = append(endpoints, createEndpointFromOperation(path, method, withPathParameters(*op, item.Parameters), security))
 		}
 	}
+	add("GET", item.Get)
+	add("POST", item.Post)
+	add("PUT", item.Put)
+	add("PATCH", item.Patch)
+	add("DELETE", item.Delete)
+	add("HEAD", item.Head)
+	add("OPTIONS", item.Options)
+	return endpoints
+}
 
-	return doc
+// withPathParameters returns op with the parameters declared on its path
+// item added ahead of its own. An operation parameter with the same name and
+// location, or the same $ref, overrides the path-level one.
+func withPathParameters(op Operation, shared []Parameter) Operation {
+	if len(shared) == 0 {
+		return op
+	}
+	key := func(p Parameter) string {
+		if p.Ref != "" {
+			return p.Ref
+		}
+		return p.In + " " + p.Name
+	}
+	own := make(map[string]bool, len(op.Parameters))
+	for _, p := range op.Parameters {
+		own[key(p)] = true
+	}
+	params := make([]Parameter, 0, len(shared)+len(op.Parameters))
+	for _, p := range shared {
+		if !own[key(p)] {
+			params = append(params, p)
+		}
+	}
+	op.Parameters = append(params, op.Parameters...)
+	return op
 }
 
 // createEndpointFromOperation creates an Endpoint from a given Operation.
-func createEndpointFromOperation(path, method string, op Operation) Endpoint {
-	return Endpoint{
+// security is the document-wide requirement, used when the operation does
+// not declare its own.
+func createEndpointFromOperation(path, method string, op Operation, security []SecurityRequirement) Endpoint {
+	// Swagger 2.0 does not have a separate RequestBody field (it uses parameters for body data).
+	params, body := extractBodyParameters(convertParameters(op.Parameters))
+	if op.RequestBody != nil {
+		body = op.RequestBody
+	}
+	ep := Endpoint{
 		Path:        path,
 		Method:      method,
 		Summary:     op.Summary,
+		OperationID: op.OperationID,
 		Description: op.Description,
-		Parameters:  convertParameters(op.Parameters),
+		Parameters:  params,
+		RequestBody: body,
 		Responses:   convertResponses(op.Responses),
-		// Swagger 2.0 does not have a separate RequestBody field (it uses parameters for body data).
+		Tags:        op.Tags,
+		CodeSamples: op.CodeSamples,
+		Idempotent:  op.Idempotent,
+		Since:       op.Since,
+		Consumes:    op.Consumes,
+		Produces:    op.Produces,
+		Servers:     op.Servers,
+		Deprecated:  op.Deprecated,
+	}
+	ep.Security, ep.SecurityScopes = operationSecurity(op, security)
+	return ep
+}
+
+// extractBodyParameters turns Swagger 2.0 "body" and "formData" parameters
+// into a synthesized request body. A body parameter's schema becomes the
+// application/json content. Form parameters become one property each of a
+// multipart/form-data body when any field is a file, and of an
+// application/x-www-form-urlencoded body otherwise.
+func extractBodyParameters(params []*Parameter) ([]*Parameter, *RequestBody) {
+	var rest []*Parameter
+	var body *RequestBody
+	form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	formMediaType := "application/x-www-form-urlencoded"
+	for _, p := range params {
+		switch p.In {
+		case "body":
+			body = &RequestBody{
+				Description: p.Description,
+				Content:     map[string]*MediaType{"application/json": {Schema: p.Schema}},
+			}
+		case "formData":
+			field := &Schema{Type: SchemaType(p.Type), Format: p.Format, Items: p.Items, Enum: p.Enum, Default: p.Default,
+				Constraints: p.Constraints}
+			if p.Type == "file" {
+				field = &Schema{Type: "string", Format: "binary"}
+				formMediaType = "multipart/form-data"
+			}
+			form.Properties[p.Name] = field
+			if p.Required {
+				form.Required = append(form.Required, p.Name)
+			}
+		default:
+			rest = append(rest, p)
+		}
+	}
+	if body == nil && len(form.Properties) > 0 {
+		body = &RequestBody{
+			Content: map[string]*MediaType{formMediaType: {Schema: form}},
+		}
 	}
+	return rest, body
 }
 
 // convertParameters converts a slice of Parameter (from Swagger) to a slice of pointers to Parameter.
@@ -245,6 +1027,27 @@ func convertResponses(responses map[string]Response) map[string]*Response {
 	result := make(map[string]*Response)
 	for code, r := range responses {
 		respCopy := r
+		if respCopy.Schema != nil && len(respCopy.Content) == 0 {
+			respCopy.Content = map[string]*MediaType{"application/json": {Schema: respCopy.Schema}}
+			respCopy.Schema = nil
+		}
+		for mediaType, example := range respCopy.Examples {
+			if respCopy.Content == nil {
+				respCopy.Content = make(map[string]*MediaType)
+			}
+			mt := respCopy.Content[mediaType]
+			if mt == nil {
+				// Swagger 2.0 declares one schema for every produced
+				// media type; it is stored under application/json.
+				mt = &MediaType{}
+				if jsonType := respCopy.Content["application/json"]; jsonType != nil {
+					mt.Schema = jsonType.Schema
+				}
+				respCopy.Content[mediaType] = mt
+			}
+			mt.Example = example
+		}
+		respCopy.Examples = nil
 		result[code] = &respCopy
 	}
 	return result
@@ -259,109 +1062,1730 @@ func minifyText(text string) string {
 // Documentation Rendering (Enhanced)
 // =====================================================
 
-// RenderText produces LLM-readable documentation for the API.
-func RenderText(doc *APIDocument) string {
-	var sb strings.Builder
+// RenderOptions controls optional parts of the rendered output. The zero
+// value renders the default layout.
+type RenderOptions struct {
+	// UnwrapSingleProperty renders envelope objects such as {"data": {...}}
+	// as their inner schema, noting "(wrapped in {data})".
+	UnwrapSingleProperty bool
+	// EnvelopeProperties lists the property names treated as envelopes by
+	// UnwrapSingleProperty. Defaults to DefaultEnvelopeProperties.
+	EnvelopeProperties []string
+	// SampleLang limits rendered x-codeSamples to one language (compared
+	// case-insensitively). Empty renders every sample.
+	SampleLang string
+	// IncludeRelationships adds a RELATED section listing the resource
+	// nesting inferred from endpoint paths.
+	IncludeRelationships bool
+	// IncludeSemantics prints whether each endpoint is safe and idempotent,
+	// as implied by its method or an x-idempotent override.
+	IncludeSemantics bool
+	// GroupByTag renders endpoints in sections by their first tag, with
+	// untagged endpoints last. Within a section endpoints are ordered by path
+	// and then method.
+	GroupByTag bool
+	// DuplicateAcrossTags, with GroupByTag, repeats an endpoint under every
+	// one of its tags instead of only the first.
+	DuplicateAcrossTags bool
+	// ConsolidateErrors renders error responses shared by several endpoints
+	// once, in a COMMON ERRORS section, instead of under every endpoint.
+	ConsolidateErrors bool
+	// DefaultTitle and DefaultVersion stand in for a missing info.title or
+	// info.version. When empty, "Untitled API" and "unknown" are used.
+	DefaultTitle   string
+	DefaultVersion string
+	// MaxVersion, when set, leaves out endpoints and schema properties,
+	// including form fields, whose x-since is newer than this version, e.g.
+	// "2.3" for a deployment that has not been upgraded past 2.3.
+	MaxVersion string
+	// EndpointHeaderTemplate formats the first line of each endpoint section.
+	// It may use the placeholders {method}, {path}, {summary} and
+	// {operationId}, e.g. "{method} {path}". Empty means
+	// DefaultEndpointHeaderTemplate. Check it with Validate; an invalid
+	// template renders with the default instead.
+	EndpointHeaderTemplate string
+	// IncludeExampleQuery adds an EXAMPLE QUERY line to endpoints with query
+	// parameters, serializing each parameter's example, default or first
+	// enum value according to its style and explode settings, e.g.
+	// "?status=available&limit=20&tags=a&tags=b".
+	IncludeExampleQuery bool
+	// MaxDescriptionLen truncates endpoint and request body descriptions
+	// longer than this many characters, marking the cut with "...". Zero
+	// means DefaultMaxDescriptionLen; a negative value disables truncation.
+	MaxDescriptionLen int
+	// InlineSchemas expands properties holding a named schema, and the
+	// items of array properties, beneath their property instead of showing
+	// the schema by name only. A schema that contains itself is cut off with
+	// "(recursive: Name)".
+	InlineSchemas bool
+	// MaxInlineDepth limits how many levels of nested properties
+	// InlineSchemas expands. Zero means DefaultMaxInlineDepth.
+	MaxInlineDepth int
+	// IncludeStats appends a STATS footer counting the rendered endpoints,
+	// overall and by method, their distinct tags and the component schemas.
+	IncludeStats bool
+	// IncludeTOC lists every endpoint as "METHOD /path" in a CONTENTS block
+	// after the header, in the same order as the endpoint sections.
+	IncludeTOC bool
+	// MaxExampleBytes truncates example bodies rendered under EXAMPLE: to
+	// about this many bytes of indented JSON, noting the cut. Zero means
+	// DefaultMaxExampleBytes; a negative value disables truncation.
+	MaxExampleBytes int
+	// WrapWidth wraps endpoint, parameter, request body and response
+	// descriptions at word boundaries so that lines stay within this many
+	// columns where possible, indenting continuation lines beneath the line
+	// they continue. Zero disables wrapping.
+	WrapWidth int
+	// OmitReadOnlyInRequests leaves readOnly properties out of request
+	// bodies, since clients must not send them. They are still listed, and
+	// marked read-only, in responses.
+	OmitReadOnlyInRequests bool
 
-	// API Header
-	sb.WriteString(fmt.Sprintf("API: %s (v%s)\n\n", doc.Title, doc.Version))
-	sb.WriteString("DESCRIPTION:\n")
-	if doc.Description != "" {
-		sb.WriteString(doc.Description)
-	} else {
-		sb.WriteString("(None or your description here)")
-	}
-	sb.WriteString("\n\n")
+	// inRequest is set while a request body is rendered.
+	inRequest bool
+}
 
-	// Process each Endpoint.
-	for _, ep := range doc.Endpoints {
-		sb.WriteString(fmt.Sprintf("ENDPOINT: %s %s\n", strings.ToUpper(ep.Method), ep.Path))
-		sb.WriteString(fmt.Sprintf("SUMMARY: %s\n", ep.Summary))
-		// Truncate endpoint description if too long.
-		desc := minifyText(ep.Description)
-		if len(desc) > 20000 {
-			desc = desc[:2000] + "..."
-		}
-		if desc == "" {
-			sb.WriteString("DESCRIPTION: (None)\n")
-		} else {
-			sb.WriteString(fmt.Sprintf("DESCRIPTION: %s\n", desc))
-		}
+// DefaultMaxInlineDepth is the nesting limit used when
+// RenderOptions.InlineSchemas is set without a MaxInlineDepth.
+const DefaultMaxInlineDepth = 8
 
-		// Parameters
-		sb.WriteString("PARAMETERS:\n")
-		if len(ep.Parameters) == 0 {
-			sb.WriteString("  (None)\n")
-		} else {
-			for _, p := range ep.Parameters {
-				// Use p.Type if present; otherwise, if a schema is provided, use that type.
-				var pType string
-				if p.Type != "" {
-					pType = p.Type
-				} else if p.Schema != nil {
-					pType = p.Schema.Type
-				} else {
-					pType = "(unknown)"
-				}
-				sb.WriteString(fmt.Sprintf("  - %s (%s, %s, required=%t)", p.Name, pType, p.In, p.Required))
-				if p.Description != "" {
-					sb.WriteString(fmt.Sprintf(" : %s", p.Description))
-				}
-				sb.WriteString("\n")
-			}
-		}
+// DefaultMaxExampleBytes is the example size limit used when
+// RenderOptions.MaxExampleBytes is zero.
+const DefaultMaxExampleBytes = 2000
 
-		// Request Body
-		sb.WriteString("REQUEST BODY: ")
-		if ep.RequestBody != nil && ep.RequestBody.Description != "" {
-			sb.WriteString(ep.RequestBody.Description)
-		} else {
-			sb.WriteString("None")
-		}
-		sb.WriteString("\n")
+// DefaultMaxDescriptionLen is the description length limit used when
+// RenderOptions.MaxDescriptionLen is zero.
+const DefaultMaxDescriptionLen = 500
 
-		// Responses
-		sb.WriteString("RESPONSES:\n")
-		if len(ep.Responses) == 0 {
-			sb.WriteString("  (None)\n")
-		} else {
-			for code, resp := range ep.Responses {
-				sb.WriteString(fmt.Sprintf("  - %s: %s\n", code, resp.Description))
+// DefaultEndpointHeaderTemplate is the endpoint header used when
+// RenderOptions.EndpointHeaderTemplate is empty.
+const DefaultEndpointHeaderTemplate = "ENDPOINT: {method} {path}"
+
+// endpointHeaderPlaceholders are the placeholders an endpoint header template
+// may use.
+var endpointHeaderPlaceholders = []string{"method", "path", "summary", "operationId"}
+
+// Validate reports configuration errors in opts, such as an unknown
+// placeholder in EndpointHeaderTemplate.
+func (opts RenderOptions) Validate() error {
+	return validateEndpointHeaderTemplate(opts.EndpointHeaderTemplate)
+}
+
+// validateEndpointHeaderTemplate checks that every {placeholder} in tmpl is
+// known and that braces are balanced.
+func validateEndpointHeaderTemplate(tmpl string) error {
+	rest := tmpl
+	for {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			return nil
+		}
+		if rest[open] == '}' {
+			return fmt.Errorf("endpoint header template %q: unexpected '}'", tmpl)
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] == '{' {
+			return fmt.Errorf("endpoint header template %q: unclosed '{'", tmpl)
+		}
+		name := rest[open+1 : open+1+end]
+		known := false
+		for _, placeholder := range endpointHeaderPlaceholders {
+			if name == placeholder {
+				known = true
+				break
 			}
 		}
-		sb.WriteString("END\n")
+		if !known {
+			return fmt.Errorf("endpoint header template %q: unknown placeholder {%s}; use one of {%s}",
+				tmpl, name, strings.Join(endpointHeaderPlaceholders, "}, {"))
+		}
+		rest = rest[open+1+end+1:]
 	}
-	return sb.String()
 }
 
-// =====================================================
-// Existing Functions for Reference Resolution
-// =====================================================
+// endpointHeader expands the endpoint header template for ep.
+func endpointHeader(ep Endpoint, tmpl string) string {
+	if tmpl == "" || validateEndpointHeaderTemplate(tmpl) != nil {
+		tmpl = DefaultEndpointHeaderTemplate
+	}
+	return strings.NewReplacer(
+		"{method}", strings.ToUpper(ep.Method),
+		"{path}", ep.Path,
+		"{summary}", minifyText(ep.Summary),
+		"{operationId}", ep.OperationID,
+	).Replace(tmpl)
+}
 
-// ResolveReferences replaces $ref fields in the document with direct pointers to Components.
-func ResolveReferences(doc *APIDocument) error {
-	if doc.Components == nil {
-		return nil
+// Built-in fallbacks for specs without a title or version.
+const (
+	fallbackTitle   = "Untitled API"
+	fallbackVersion = "unknown"
+)
+
+// headerTitleVersion returns the title and version label for the header,
+// e.g. "Pet Store" and "v1.2", substituting defaults for blank values.
+func headerTitleVersion(doc *APIDocument, opts RenderOptions) (string, string) {
+	title := strings.TrimSpace(doc.Title)
+	if title == "" {
+		title = strings.TrimSpace(opts.DefaultTitle)
+	}
+	if title == "" {
+		title = fallbackTitle
 	}
 
-	for i := range doc.Endpoints {
-		ep := &doc.Endpoints[i]
+	version := strings.TrimSpace(doc.Version)
+	if version == "" {
+		version = strings.TrimSpace(opts.DefaultVersion)
+	}
+	if version == "" {
+		return title, "version " + fallbackVersion
+	}
+	return title, "v" + version
+}
 
-		// Resolve parameters.
-		for j, param := range ep.Parameters {
-			if param == nil {
-				continue
-			}
-			if param.Ref != "" {
-				refName := extractNameFromRef(param.Ref, "parameters")
-				if resolved, ok := doc.Components.Parameters[refName]; ok {
-					ep.Parameters[j] = resolved
-				} else {
-					errMsg := fmt.Sprintf("unresolved parameter reference: %s", param.Ref)
-					return fmt.Errorf(errMsg)
-				}
+// DefaultEnvelopeProperties are the wrapper property names unwrapped when
+// RenderOptions.EnvelopeProperties is empty.
+var DefaultEnvelopeProperties = []string{"data", "result", "payload"}
+
+// RenderText produces LLM-readable documentation for the API.
+func RenderText(doc *APIDocument) string {
+	return RenderTextWithOptions(doc, RenderOptions{})
+}
+
+// RenderTextGrouped is RenderText with endpoints in sections by their first
+// tag, followed by an "Untagged" section; see RenderOptions.GroupByTag.
+func RenderTextGrouped(doc *APIDocument) string {
+	return RenderTextWithOptions(doc, RenderOptions{GroupByTag: true})
+}
+
+// RenderTextWithOptions is RenderText with a custom configuration.
+func RenderTextWithOptions(doc *APIDocument, opts RenderOptions) string {
+	var buf bytes.Buffer
+	// Writes to a bytes.Buffer cannot fail.
+	_ = RenderTextToWithOptions(&buf, doc, opts)
+	return buf.String()
+}
+
+// RenderTextTo writes the RenderText output for doc to w as it is produced,
+// one endpoint at a time, so large specs are never held in memory whole. It
+// returns the first write error.
+func RenderTextTo(w io.Writer, doc *APIDocument) error {
+	return RenderTextToWithOptions(w, doc, RenderOptions{})
+}
+
+// RenderTextToWithOptions is RenderTextTo with a custom configuration.
+func RenderTextToWithOptions(w io.Writer, doc *APIDocument, opts RenderOptions) error {
+	var sb strings.Builder
+	flush := func() error {
+		_, err := io.WriteString(w, sb.String())
+		sb.Reset()
+		return err
+	}
+	if opts.MaxVersion != "" {
+		doc = filterEndpoints(doc, func(ep Endpoint) bool {
+			return !newerVersion(ep.Since, opts.MaxVersion)
+		})
+	}
+
+	renderHeader(&sb, doc, opts)
+
+	if opts.IncludeRelationships {
+		if relationships := InferRelationships(doc); len(relationships) > 0 {
+			sb.WriteString("RELATED:\n")
+			for _, rel := range relationships {
+				sb.WriteString(fmt.Sprintf("  - %s are nested under %s (by %s)\n", rel.Child, rel.Parent, rel.Via))
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	r := &textRenderer{opts: opts, doc: doc}
+	if opts.ConsolidateErrors {
+		common := commonErrorResponses(doc.Endpoints)
+		if len(common) > 0 {
+			r.commonErrors = make(map[string]bool, len(common))
+			sb.WriteString("COMMON ERRORS:\n")
+			for _, ce := range common {
+				r.commonErrors[ce.key] = true
+				sb.WriteString(fmt.Sprintf("  - %s: %s\n", ce.code, ce.response.Description))
+				renderContentSchemas(&sb, ce.response.Content, "    ", opts)
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	var groups []tagGroup
+	if opts.GroupByTag {
+		groups = groupEndpointsByTag(doc.Endpoints, opts.DuplicateAcrossTags)
+	}
+	if opts.IncludeTOC {
+		renderTOC(&sb, doc.Endpoints, groups)
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	// Process each Endpoint.
+	if opts.GroupByTag {
+		for _, group := range groups {
+			sb.WriteString(fmt.Sprintf("TAG: %s\n\n", group.Tag))
+			for _, ep := range group.Endpoints {
+				var otherTags []string
+				if opts.DuplicateAcrossTags {
+					for _, tag := range ep.Tags {
+						if tag != group.Tag {
+							otherTags = append(otherTags, tag)
+						}
+					}
+				}
+				r.renderEndpoint(&sb, ep, otherTags)
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			sb.WriteString("\n")
+		}
+	} else {
+		for _, ep := range doc.Endpoints {
+			r.renderEndpoint(&sb, ep, nil)
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if opts.IncludeStats {
+		renderStats(&sb, doc)
+	}
+	return flush()
+}
+
+// renderHeader writes the API header: title and version, any deprecation
+// note and terms of service, the description and the servers.
+func renderHeader(sb *strings.Builder, doc *APIDocument, opts RenderOptions) {
+	title, version := headerTitleVersion(doc, opts)
+	sb.WriteString(fmt.Sprintf("API: %s (%s)\n\n", title, version))
+	if doc.Deprecated || doc.ReplacedBy != "" {
+		sb.WriteString(fmt.Sprintf("NOTE: This API version (%s) is deprecated", version))
+		if doc.ReplacedBy != "" {
+			sb.WriteString(fmt.Sprintf("; migrate to %s", doc.ReplacedBy))
+		}
+		sb.WriteString(".\n\n")
+	}
+	if terms := strings.TrimSpace(doc.TermsOfService); terms != "" {
+		sb.WriteString(fmt.Sprintf("TERMS: %s\n\n", terms))
+	}
+	sb.WriteString("DESCRIPTION:\n")
+	if doc.Description != "" {
+		sb.WriteString(doc.Description)
+	} else {
+		sb.WriteString("(None or your description here)")
+	}
+	sb.WriteString("\n\n")
+
+	sb.WriteString("SERVERS:\n")
+	if len(doc.Servers) == 0 {
+		sb.WriteString("  (none specified)\n")
+	}
+	for _, server := range doc.Servers {
+		sb.WriteString(fmt.Sprintf("  - %s\n", serverList([]Server{server})))
+	}
+	sb.WriteString("\n")
+}
+
+// renderTOC writes the CONTENTS block listing every endpoint as
+// "METHOD /path" in the order the endpoint sections follow: under their tag
+// when grouped is non-nil, and in document order otherwise.
+func renderTOC(sb *strings.Builder, endpoints []Endpoint, grouped []tagGroup) {
+	sb.WriteString("CONTENTS:\n")
+	if grouped == nil {
+		for _, ep := range endpoints {
+			sb.WriteString(fmt.Sprintf("  - %s %s\n", strings.ToUpper(ep.Method), ep.Path))
+		}
+	}
+	for _, group := range grouped {
+		sb.WriteString(fmt.Sprintf("  %s:\n", group.Tag))
+		for _, ep := range group.Endpoints {
+			sb.WriteString(fmt.Sprintf("    - %s %s\n", strings.ToUpper(ep.Method), ep.Path))
+		}
+	}
+	sb.WriteString("\n")
+}
+
+// renderStats writes the STATS footer for doc, e.g.
+// "STATS: endpoints: 5 (GET 3, POST 2), tags: 2, component schemas: 4".
+func renderStats(sb *strings.Builder, doc *APIDocument) {
+	byMethod := make(map[string]int)
+	tags := make(map[string]bool)
+	for _, ep := range doc.Endpoints {
+		byMethod[strings.ToUpper(ep.Method)]++
+		for _, tag := range ep.Tags {
+			tags[tag] = true
+		}
+	}
+	methods := make([]string, 0, len(byMethod))
+	for _, method := range canonicalMethods {
+		if byMethod[method] > 0 {
+			methods = append(methods, fmt.Sprintf("%s %d", method, byMethod[method]))
+			delete(byMethod, method)
+		}
+	}
+	others := make([]string, 0, len(byMethod))
+	for method := range byMethod {
+		others = append(others, method)
+	}
+	sort.Strings(others)
+	for _, method := range others {
+		methods = append(methods, fmt.Sprintf("%s %d", method, byMethod[method]))
+	}
+
+	schemas := 0
+	if doc.Components != nil {
+		schemas = len(doc.Components.Schemas)
+	}
+	sb.WriteString(fmt.Sprintf("\nSTATS: endpoints: %d", len(doc.Endpoints)))
+	if len(methods) > 0 {
+		sb.WriteString(" (" + strings.Join(methods, ", ") + ")")
+	}
+	sb.WriteString(fmt.Sprintf(", tags: %d, component schemas: %d\n", len(tags), schemas))
+}
+
+// textRenderer holds the state shared by the endpoint sections of a
+// RenderTextWithOptions call.
+type textRenderer struct {
+	opts RenderOptions
+	// doc supplies the document-level defaults endpoints inherit.
+	doc *APIDocument
+	// commonErrors holds the keys of error responses rendered once in the
+	// COMMON ERRORS section; see errorResponseKey.
+	commonErrors map[string]bool
+}
+
+// renderEndpoint writes the section for a single endpoint. otherTags lists
+// the other tag groups the endpoint is repeated under, if any.
+func (r *textRenderer) renderEndpoint(sb *strings.Builder, ep Endpoint, otherTags []string) {
+	opts := r.opts
+	sb.WriteString(endpointHeader(ep, opts.EndpointHeaderTemplate) + sinceNote(ep.Since))
+	if ep.Deprecated {
+		sb.WriteString(" [DEPRECATED]")
+	}
+	sb.WriteString("\n")
+	if len(otherTags) > 0 {
+		sb.WriteString(fmt.Sprintf("ALSO LISTED UNDER: %s\n", strings.Join(otherTags, ", ")))
+	}
+	if ep.Source != "" {
+		sb.WriteString(fmt.Sprintf("SOURCE: %s\n", ep.Source))
+	}
+	if ep.OperationID != "" {
+		sb.WriteString(fmt.Sprintf("OPERATION ID: %s\n", ep.OperationID))
+	}
+	sb.WriteString(fmt.Sprintf("SUMMARY: %s\n", ep.Summary))
+	desc := formatDescription(ep.Description, opts.MaxDescriptionLen)
+	if desc == "" {
+		sb.WriteString("DESCRIPTION: (None)\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("DESCRIPTION: %s\n", wrapText(desc, len("DESCRIPTION: "), "  ", opts.WrapWidth)))
+	}
+	if opts.IncludeSemantics {
+		safe, idempotent := methodSemantics(ep)
+		sb.WriteString(fmt.Sprintf("SAFE: %s, IDEMPOTENT: %s\n", yesNo(safe), yesNo(idempotent)))
+	}
+	if len(ep.Servers) > 0 {
+		sb.WriteString(fmt.Sprintf("SERVERS: %s\n", serverList(ep.Servers)))
+	}
+	var schemes map[string]*SecurityScheme
+	if r.doc.Components != nil {
+		schemes = r.doc.Components.SecuritySchemes
+	}
+	if len(ep.Security) > 0 || len(schemes) > 0 {
+		sb.WriteString(fmt.Sprintf("SECURITY: %s\n", securityLine(ep.Security, ep.SecurityScopes, schemes)))
+	}
+	if ep.RequestBody != nil {
+		renderMediaTypeList(sb, "ACCEPTS", ep.Consumes, r.doc.Consumes)
+	}
+	renderMediaTypeList(sb, "RETURNS", ep.Produces, r.doc.Produces)
+
+	// Parameters
+	sb.WriteString("PARAMETERS:\n")
+	if len(ep.Parameters) == 0 {
+		sb.WriteString("  (None)\n")
+	} else {
+		for _, p := range ep.Parameters {
+			if p.Ref != "" && p.Name == "" {
+				sb.WriteString(fmt.Sprintf("  - %s\n", unresolvedNote(p.Ref)))
+				continue
+			}
+			line := fmt.Sprintf("  - %s (%s, %s, required=%t%s%s%s%s)", p.Name, parameterType(p), p.In, p.Required,
+				enumNote(parameterEnum(p)), constraintNote(parameterConstraints(p)), collectionFormatNote(p),
+				parameterValuesNote(p))
+			line += deprecationNote(p.Deprecated, p.ReplacedBy)
+			if p.Description != "" {
+				line += " : "
+				line += wrapText(p.Description, len(line), "    ", opts.WrapWidth)
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	if opts.IncludeExampleQuery {
+		if query := exampleQuery(ep); query != "" {
+			sb.WriteString(fmt.Sprintf("EXAMPLE QUERY: %s\n", query))
+		}
+	}
+
+	// Request Body
+	sb.WriteString("REQUEST BODY: ")
+	switch {
+	case ep.RequestBody == nil:
+		sb.WriteString("None")
+	case ep.RequestBody.Ref != "":
+		sb.WriteString(unresolvedNote(ep.RequestBody.Ref))
+	case ep.RequestBody.Description != "":
+		sb.WriteString(wrapText(formatDescription(ep.RequestBody.Description, opts.MaxDescriptionLen), len("REQUEST BODY: "), "  ", opts.WrapWidth))
+	case len(ep.RequestBody.Content) == 0:
+		sb.WriteString("None")
+	default:
+		sb.WriteString("(no description)")
+	}
+	sb.WriteString("\n")
+	if ep.RequestBody != nil {
+		requestOpts := opts
+		requestOpts.inRequest = true
+		renderContentSchemas(sb, ep.RequestBody.Content, "  ", requestOpts)
+		renderVariants(sb, ep.RequestBody.Content)
+		renderFormFields(sb, ep.RequestBody.Content, requestOpts)
+	}
+
+	// Responses
+	sb.WriteString("RESPONSES:\n")
+	if len(ep.Responses) == 0 {
+		sb.WriteString("  (None)\n")
+	} else {
+		var common []string
+		for _, code := range sortedResponseCodes(ep.Responses) {
+			resp := ep.Responses[code]
+			if r.commonErrors[errorResponseKey(code, resp)] {
+				common = append(common, code)
+				continue
+			}
+			label := code
+			if code == "default" {
+				label = "default (any other status)"
+			}
+			if resp.Ref != "" {
+				sb.WriteString(fmt.Sprintf("  - %s: %s\n", label, unresolvedNote(resp.Ref)))
+				continue
+			}
+			prefix := fmt.Sprintf("  - %s: ", label)
+			sb.WriteString(prefix + wrapText(resp.Description, len(prefix), "    ", opts.WrapWidth) + "\n")
+			if len(resp.Headers) > 0 {
+				sb.WriteString(fmt.Sprintf("    headers: %s\n", responseHeaders(resp.Headers)))
+			}
+			if len(resp.Content) == 0 {
+				sb.WriteString("    (no body)\n")
+			}
+			renderContentSchemas(sb, resp.Content, "    ", opts)
+		}
+		if len(common) > 0 {
+			sb.WriteString(fmt.Sprintf("  (plus common errors: %s)\n", strings.Join(common, ", ")))
+		}
+	}
+	renderCodeSamples(sb, ep.CodeSamples, opts.SampleLang)
+	sb.WriteString("END\n")
+}
+
+// responseHeaders lists response headers by name with their types, e.g.
+// "Location (string), X-Rate-Limit (integer)".
+func responseHeaders(headers map[string]*Parameter) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		header := headers[name]
+		switch {
+		case header == nil:
+			parts = append(parts, name)
+		case header.Ref != "":
+			parts = append(parts, fmt.Sprintf("%s %s", name, unresolvedNote(header.Ref)))
+		default:
+			parts = append(parts, fmt.Sprintf("%s (%s)", name, parameterType(header)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// minCommonErrorEndpoints is how many endpoints must share an error response
+// before ConsolidateErrors moves it to the COMMON ERRORS section.
+const minCommonErrorEndpoints = 3
+
+// commonErrorResponse is an error response shared by several endpoints.
+type commonErrorResponse struct {
+	key      string
+	code     string
+	response *Response
+}
+
+// commonErrorResponses finds the 4xx/5xx responses that are declared
+// identically, by code, description and content schemas, on at least
+// minCommonErrorEndpoints endpoints. They are returned sorted by code.
+func commonErrorResponses(endpoints []Endpoint) []commonErrorResponse {
+	counts := make(map[string]int)
+	first := make(map[string]commonErrorResponse)
+	for _, ep := range endpoints {
+		for code, resp := range ep.Responses {
+			key := errorResponseKey(code, resp)
+			if key == "" {
+				continue
+			}
+			counts[key]++
+			if _, ok := first[key]; !ok {
+				first[key] = commonErrorResponse{key: key, code: code, response: resp}
+			}
+		}
+	}
+
+	var common []commonErrorResponse
+	for key, count := range counts {
+		if count >= minCommonErrorEndpoints {
+			common = append(common, first[key])
+		}
+	}
+	sort.Slice(common, func(i, j int) bool {
+		if common[i].code != common[j].code {
+			return common[i].code < common[j].code
+		}
+		return common[i].key < common[j].key
+	})
+	return common
+}
+
+// errorResponseKey identifies an error response by its code, description and
+// content schemas. It returns "" for responses that are not 4xx or 5xx.
+func errorResponseKey(code string, resp *Response) string {
+	if resp == nil || !(strings.HasPrefix(code, "4") || strings.HasPrefix(code, "5")) {
+		return ""
+	}
+	parts := []string{code, minifyText(resp.Description)}
+	for _, mediaType := range sortedMediaTypes(resp.Content) {
+		var schema *Schema
+		if mt := resp.Content[mediaType]; mt != nil {
+			schema = mt.Schema
+		}
+		parts = append(parts, mediaType+"="+schemaSummary(schema))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// untaggedGroup is the name of the group holding endpoints without tags.
+const untaggedGroup = "Untagged"
+
+// tagGroup is a named section of endpoints sharing a tag.
+type tagGroup struct {
+	Tag       string
+	Endpoints []Endpoint
+}
+
+// groupEndpointsByTag sorts endpoints into groups by their first tag, or by
+// every tag when duplicate is set. Groups are ordered by tag name with the
+// untagged group last, and endpoints within a group by path then method.
+func groupEndpointsByTag(endpoints []Endpoint, duplicate bool) []tagGroup {
+	byTag := make(map[string][]Endpoint)
+	for _, ep := range endpoints {
+		tags := ep.Tags
+		if len(tags) == 0 {
+			tags = []string{untaggedGroup}
+		} else if !duplicate {
+			tags = tags[:1]
+		}
+		for _, tag := range tags {
+			byTag[tag] = append(byTag[tag], ep)
+		}
+	}
+
+	names := make([]string, 0, len(byTag))
+	for tag := range byTag {
+		if tag != untaggedGroup {
+			names = append(names, tag)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := byTag[untaggedGroup]; ok {
+		names = append(names, untaggedGroup)
+	}
+
+	groups := make([]tagGroup, 0, len(names))
+	for _, tag := range names {
+		eps := byTag[tag]
+		sort.SliceStable(eps, func(i, j int) bool {
+			if eps[i].Path != eps[j].Path {
+				return eps[i].Path < eps[j].Path
+			}
+			return methodRank(eps[i].Method) < methodRank(eps[j].Method)
+		})
+		groups = append(groups, tagGroup{Tag: tag, Endpoints: eps})
+	}
+	return groups
+}
+
+// canonicalMethods lists HTTP methods in the order endpoints sharing a path
+// are rendered.
+var canonicalMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// methodRank returns the position of method in canonicalMethods; unknown
+// methods sort last.
+func methodRank(method string) int {
+	for i, m := range canonicalMethods {
+		if strings.EqualFold(m, method) {
+			return i
+		}
+	}
+	return len(canonicalMethods)
+}
+
+// methodSemantics reports whether an endpoint is safe (read-only) and
+// idempotent according to RFC 9110 method semantics. An x-idempotent
+// extension overrides the idempotency.
+func methodSemantics(ep Endpoint) (safe, idempotent bool) {
+	switch strings.ToUpper(ep.Method) {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		safe, idempotent = true, true
+	case "PUT", "DELETE":
+		idempotent = true
+	}
+	if ep.Idempotent != nil {
+		idempotent = *ep.Idempotent
+	}
+	return safe, idempotent
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// deprecationNote returns " [deprecated]", or " [deprecated, use 'x'
+// instead]" when a replacement is known, and "" for fields still in use.
+func deprecationNote(deprecated bool, replacedBy string) string {
+	switch {
+	case replacedBy != "":
+		return fmt.Sprintf(" [deprecated, use '%s' instead]", replacedBy)
+	case deprecated:
+		return " [deprecated]"
+	}
+	return ""
+}
+
+// sinceNote returns " (since v2.3)" for a field or endpoint introduced in
+// version 2.3, and "" when the version is unknown.
+func sinceNote(since string) string {
+	since = strings.TrimPrefix(strings.TrimSpace(since), "v")
+	if since == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (since v%s)", since)
+}
+
+// newerVersion reports whether version is later than max. Versions are
+// compared segment by segment on their dot-separated parts, numerically
+// where both parts are numbers, so "2.10" is newer than "2.9". An empty
+// version is never newer.
+func newerVersion(version, max string) bool {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	max = strings.TrimPrefix(strings.TrimSpace(max), "v")
+	if version == "" || max == "" {
+		return false
+	}
+	a, b := strings.Split(version, "."), strings.Split(max, ".")
+	for i := 0; i < len(a) || i < len(b); i++ {
+		x, y := "0", "0"
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x == y {
+			continue
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		if xerr == nil && yerr == nil {
+			return xn > yn
+		}
+		return x > y
+	}
+	return false
+}
+
+// renderMediaTypeList writes an endpoint's effective media types, e.g.
+// "ACCEPTS: application/json (inherited)": its own list when it declares one,
+// otherwise the document default. Nothing is written when neither is set.
+func renderMediaTypeList(sb *strings.Builder, label string, own, inherited []string) {
+	switch {
+	case len(own) > 0:
+		sb.WriteString(fmt.Sprintf("%s: %s\n", label, strings.Join(own, ", ")))
+	case len(inherited) > 0:
+		sb.WriteString(fmt.Sprintf("%s: %s (inherited)\n", label, strings.Join(inherited, ", ")))
+	}
+}
+
+// parameterType returns the declared type of a parameter: its own type if
+// present, otherwise the type of its schema, with any format. Arrays name
+// their element type in brackets, e.g. "array[string]" or
+// "array[integer<int64>]"; angle brackets are kept for formats, as in
+// typeWithFormat, so the two never nest ambiguously.
+func parameterType(p *Parameter) string {
+	typ, format, items := SchemaType(p.Type), p.Format, p.Items
+	if p.Schema != nil {
+		if typ == "" {
+			typ, format = p.Schema.Type, p.Schema.Format
+		}
+		if items == nil {
+			items = p.Schema.Items
+		}
+	}
+	nullable := typ.Nullable() || p.Schema != nil && p.Schema.Nullable
+	switch {
+	case typ.Base() == "array" && items != nil:
+		return withNull(fmt.Sprintf("array[%s]", schemaSummary(items)), nullable)
+	case typ != "":
+		return withNull(typeWithFormat(typ.Base(), format), nullable)
+	}
+	return "(unknown)"
+}
+
+// parameterConstraints returns the validation constraints of a parameter,
+// taking each from the parameter itself (Swagger 2.0) or else its schema.
+func parameterConstraints(p *Parameter) Constraints {
+	c := p.Constraints
+	if s := p.Schema; s != nil {
+		if c.Minimum == nil {
+			c.Minimum = s.Minimum
+		}
+		if c.Maximum == nil {
+			c.Maximum = s.Maximum
+		}
+		if c.MinLength == nil {
+			c.MinLength = s.MinLength
+		}
+		if c.MaxLength == nil {
+			c.MaxLength = s.MaxLength
+		}
+		if c.Pattern == "" {
+			c.Pattern = s.Pattern
+		}
+	}
+	return c
+}
+
+// constraintNote renders the constraints that are present compactly, e.g.
+// ", 0..120", ", >= 1", ", len 1..50" or ", pattern ^[a-z]+$".
+func constraintNote(c Constraints) string {
+	var note string
+	if r := rangeNote(c.Minimum, c.Maximum); r != "" {
+		note += ", " + r
+	}
+	var minLen, maxLen *float64
+	if c.MinLength != nil {
+		v := float64(*c.MinLength)
+		minLen = &v
+	}
+	if c.MaxLength != nil {
+		v := float64(*c.MaxLength)
+		maxLen = &v
+	}
+	if r := rangeNote(minLen, maxLen); r != "" {
+		note += ", len " + r
+	}
+	if c.Pattern != "" {
+		note += ", pattern " + c.Pattern
+	}
+	return note
+}
+
+// rangeNote renders inclusive bounds as "min..max", ">= min" or "<= max",
+// or "" when neither is set.
+func rangeNote(min, max *float64) string {
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	switch {
+	case min != nil && max != nil:
+		return format(*min) + ".." + format(*max)
+	case min != nil:
+		return ">= " + format(*min)
+	case max != nil:
+		return "<= " + format(*max)
+	}
+	return ""
+}
+
+// collectionFormats explains how each Swagger 2.0 collectionFormat sends
+// the values of an array parameter.
+var collectionFormats = map[string]string{
+	"csv":   "comma-separated",
+	"ssv":   "space-separated",
+	"tsv":   "tab-separated",
+	"pipes": "pipe-separated",
+	"multi": "one name=value pair per value",
+}
+
+// collectionFormatNote notes the collectionFormat of a Swagger 2.0 array
+// parameter, e.g. ", collectionFormat: multi (one name=value pair per
+// value)", or returns "" when none is declared.
+func collectionFormatNote(p *Parameter) string {
+	if p.CollectionFormat == "" {
+		return ""
+	}
+	if meaning, ok := collectionFormats[p.CollectionFormat]; ok {
+		return fmt.Sprintf(", collectionFormat: %s (%s)", p.CollectionFormat, meaning)
+	}
+	return ", collectionFormat: " + p.CollectionFormat
+}
+
+// parameterEnum returns the allowed values of a parameter, whether declared on
+// the parameter itself (Swagger 2.0), on its schema, or on its array items.
+func parameterEnum(p *Parameter) []interface{} {
+	switch {
+	case len(p.Enum) > 0:
+		return p.Enum
+	case p.Schema != nil && len(p.Schema.Enum) > 0:
+		return p.Schema.Enum
+	case p.Schema != nil && p.Schema.Items != nil && len(p.Schema.Items.Enum) > 0:
+		return p.Schema.Items.Enum
+	case p.Items != nil:
+		return p.Items.Enum
+	}
+	return nil
+}
+
+// parameterValuesNote returns ", default: X, example: Y" for the default and
+// example of a parameter or, failing that, of its schema. Unset values are
+// left out.
+func parameterValuesNote(p *Parameter) string {
+	def, example := p.Default, p.Example
+	if p.Schema != nil {
+		if def == nil {
+			def = p.Schema.Default
+		}
+		if example == nil {
+			example = p.Schema.Example
+		}
+	}
+	var note string
+	if def != nil {
+		note += ", default: " + formatValue(def)
+	}
+	if example != nil {
+		note += ", example: " + formatValue(example)
+	}
+	return note
+}
+
+// formatValue formats a spec value for a rendered line: scalars with %v, and
+// arrays and objects as compact JSON.
+func formatValue(v interface{}) string {
+	switch v := toJSONValue(v).(type) {
+	case map[string]interface{}, []interface{}:
+		if encoded, err := json.Marshal(v); err == nil {
+			return string(encoded)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// enumNote returns ", allowed values: a, b, c" for an enum, formatting
+// numbers, booleans and null generically, and "" when enum is empty.
+func enumNote(enum []interface{}) string {
+	if len(enum) == 0 {
+		return ""
+	}
+	values := make([]string, 0, len(enum))
+	for _, v := range enum {
+		if v == nil {
+			values = append(values, "null")
+			continue
+		}
+		values = append(values, fmt.Sprintf("%v", v))
+	}
+	return ", allowed values: " + strings.Join(values, ", ")
+}
+
+// serverList formats servers as "url (description), url".
+func serverList(servers []Server) string {
+	list := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if server.Description != "" {
+			list = append(list, fmt.Sprintf("%s (%s)", server.URL, minifyText(server.Description)))
+		} else {
+			list = append(list, server.URL)
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// renderCodeSamples writes an endpoint's x-codeSamples, optionally limited to
+// a single language. Nothing is written when no sample matches.
+func renderCodeSamples(sb *strings.Builder, samples []CodeSample, lang string) {
+	header := false
+	for _, sample := range samples {
+		if lang != "" && !strings.EqualFold(sample.Lang, lang) {
+			continue
+		}
+		if !header {
+			sb.WriteString("CODE SAMPLES:\n")
+			header = true
+		}
+		label := sample.Lang
+		if sample.Label != "" && sample.Label != sample.Lang {
+			label = fmt.Sprintf("%s, %s", sample.Lang, sample.Label)
+		}
+		sb.WriteString(fmt.Sprintf("  [%s]\n", label))
+		for _, line := range strings.Split(strings.TrimRight(sample.Source, "\n"), "\n") {
+			sb.WriteString("    " + line + "\n")
+		}
+	}
+}
+
+// renderContentSchemas writes one line per media type naming the schema of
+// its payload, e.g. "application/json: Pet (object)". Media types without a
+// schema are listed on their own.
+func renderContentSchemas(sb *strings.Builder, content map[string]*MediaType, indent string, opts RenderOptions) {
+	for _, mediaType := range sortedMediaTypes(content) {
+		mt := content[mediaType]
+		if mt == nil || mt.Schema == nil {
+			sb.WriteString(fmt.Sprintf("%s%s\n", indent, mediaType))
+			if mt != nil {
+				renderExampleBody(sb, mt.Example, indent+"  ", opts.MaxExampleBytes)
+				renderNamedExamples(sb, mt.Examples, indent+"  ")
+			}
+			continue
+		}
+		schema, note := mt.Schema, ""
+		if opts.UnwrapSingleProperty {
+			if inner, wrapper := unwrapEnvelope(schema, opts.EnvelopeProperties); inner != nil {
+				schema, note = inner, fmt.Sprintf(" (wrapped in {%s})", wrapper)
+			}
+		}
+		summary := schemaSummary(schema)
+		if schema.Name != "" && schema.Type != "" {
+			summary += fmt.Sprintf(" (%s)", schema.Type)
+		}
+		if isXMLMediaType(mediaType) {
+			note += xmlRootNote(schema)
+		}
+		sb.WriteString(fmt.Sprintf("%s%s: %s%s\n", indent, mediaType, summary, note))
+		if !isFormMediaType(mediaType) {
+			fields := schema
+			if schema.Type.Base() == "array" && schema.Items != nil {
+				fields = schema.Items
+			}
+			renderSchemaProperties(sb, fields, indent+"  ", 1, opts, make(map[*Schema]bool))
+		}
+		example := mt.Example
+		if example == nil {
+			example = mt.Schema.Example
+		}
+		renderExampleBody(sb, example, indent+"  ", opts.MaxExampleBytes)
+		renderNamedExamples(sb, mt.Examples, indent+"  ")
+	}
+}
+
+// maxPropertyDepth limits how many levels of inline objects
+// renderSchemaProperties expands when RenderOptions.InlineSchemas is off.
+const maxPropertyDepth = 3
+
+// renderSchemaProperties writes one line per property of s with its type and
+// whether it is required, e.g. "- name (string, required)". Inline objects
+// are expanded beneath their property up to maxPropertyDepth; properties
+// holding a named schema are shown by name only, which also keeps
+// self-referencing schemas finite. With opts.InlineSchemas, named schemas and
+// array items are expanded too, up to opts.MaxInlineDepth, and ancestors,
+// the schemas being expanded on the current path, stop cycles.
+func renderSchemaProperties(sb *strings.Builder, s *Schema, indent string, depth int, opts RenderOptions, ancestors map[*Schema]bool) {
+	maxDepth := maxPropertyDepth
+	if opts.InlineSchemas {
+		maxDepth = opts.MaxInlineDepth
+		if maxDepth <= 0 {
+			maxDepth = DefaultMaxInlineDepth
+		}
+	}
+	if s == nil || len(s.Properties) == 0 || depth > maxDepth {
+		return
+	}
+	ancestors[s] = true
+	defer delete(ancestors, s)
+
+	required := make(map[string]bool, len(s.Required))
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := s.Properties[name]
+		if omitProperty(prop, opts) {
+			continue
+		}
+		label := schemaSummary(prop)
+		if prop != nil && prop.Ref != "" {
+			label = "unresolved: " + prop.Ref
+		}
+		if required[name] {
+			label += ", required"
+		}
+		var note string
+		if prop != nil {
+			label += accessNote(prop) + enumNote(prop.Enum) + constraintNote(prop.Constraints)
+			note = sinceNote(prop.Since) + deprecationNote(prop.Deprecated, prop.ReplacedBy)
+		}
+
+		expand := prop
+		if opts.InlineSchemas && prop != nil && prop.Type.Base() == "array" && prop.Items != nil {
+			expand = prop.Items
+		}
+		recursive := opts.InlineSchemas && ancestors[expand]
+		if recursive {
+			note += fmt.Sprintf(" (recursive: %s)", schemaSummary(expand))
+		}
+		sb.WriteString(fmt.Sprintf("%s- %s (%s)%s\n", indent, name, label, note))
+
+		switch {
+		case expand == nil || recursive || expand.Ref != "":
+		case opts.InlineSchemas || expand.Name == "":
+			renderSchemaProperties(sb, expand, indent+"  ", depth+1, opts, ancestors)
+		}
+	}
+}
+
+// accessNote marks a property that only appears in responses,
+// ", read-only", or only in requests, ", write-only".
+func accessNote(s *Schema) string {
+	switch {
+	case s.ReadOnly:
+		return ", read-only"
+	case s.WriteOnly:
+		return ", write-only"
+	}
+	return ""
+}
+
+// omitProperty reports whether a property is left out of the rendered
+// schema: one introduced after RenderOptions.MaxVersion, or a readOnly
+// property of a request body when RenderOptions.OmitReadOnlyInRequests is
+// set.
+func omitProperty(prop *Schema, opts RenderOptions) bool {
+	if prop == nil {
+		return false
+	}
+	if opts.MaxVersion != "" && newerVersion(prop.Since, opts.MaxVersion) {
+		return true
+	}
+	return prop.ReadOnly && opts.inRequest && opts.OmitReadOnlyInRequests
+}
+
+// isFormMediaType reports whether mediaType is one of formMediaTypes, whose
+// fields renderFormFields lists instead.
+func isFormMediaType(mediaType string) bool {
+	base := baseMediaType(mediaType)
+	for _, form := range formMediaTypes {
+		if base == form {
+			return true
+		}
+	}
+	return false
+}
+
+// isXMLMediaType reports whether mediaType carries XML, such as
+// application/xml, text/xml or application/atom+xml.
+func isXMLMediaType(mediaType string) bool {
+	base := baseMediaType(mediaType)
+	return strings.HasSuffix(base, "/xml") || strings.HasSuffix(base, "+xml")
+}
+
+// baseMediaType returns mediaType in lower case without parameters, e.g.
+// "application/x-www-form-urlencoded" for
+// "application/x-www-form-urlencoded; charset=UTF-8".
+func baseMediaType(mediaType string) string {
+	if base, _, err := mime.ParseMediaType(mediaType); err == nil {
+		return base
+	}
+	base, _, _ := strings.Cut(mediaType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
+}
+
+// xmlRootNote names the root element of an XML body, e.g. " (root element
+// <pet>)", from the schema's xml name or else its component name. Array
+// bodies are named by the wrapping element, or by their items when those
+// are not wrapped.
+func xmlRootNote(s *Schema) string {
+	name := s.Name
+	if s.XML != nil && s.XML.Name != "" {
+		name = s.XML.Name
+	}
+	if s.Type.Base() == "array" && s.Items != nil && (s.XML == nil || !s.XML.Wrapped) {
+		name = s.Items.Name
+		if s.Items.XML != nil && s.Items.XML.Name != "" {
+			name = s.Items.XML.Name
+		}
+		if name != "" {
+			return fmt.Sprintf(" (repeated element <%s>)", name)
+		}
+	}
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (root element <%s>)", name)
+}
+
+// renderExampleBody writes an example body as indented JSON under an
+// EXAMPLE: label. A string example, such as an XML document, is written as
+// is. Output longer than maxBytes is cut at the last whole line within the
+// limit and followed by a note giving the full size; see
+// RenderOptions.MaxExampleBytes.
+func renderExampleBody(sb *strings.Builder, example interface{}, indent string, maxBytes int) {
+	if example == nil {
+		return
+	}
+	var body string
+	if text, ok := example.(string); ok {
+		body = strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n"+indent)
+	} else {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent(indent, "  ")
+		if err := enc.Encode(toJSONValue(example)); err != nil {
+			body = fmt.Sprintf("%v", example)
+		} else {
+			body = strings.TrimSuffix(buf.String(), "\n")
+		}
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultMaxExampleBytes
+	}
+	if maxBytes > 0 && len(body) > maxBytes {
+		cut := body[:maxBytes]
+		if i := strings.LastIndex(cut, "\n"); i > 0 {
+			cut = cut[:i]
+		}
+		body = fmt.Sprintf("%s\n%s... (truncated, %d bytes in full)", cut, indent, len(body))
+	}
+	sb.WriteString(fmt.Sprintf("%sEXAMPLE:\n%s%s\n", indent, indent, body))
+}
+
+// renderNamedExamples writes each named example on its own line, labeled by
+// its summary or, failing that, its key in the examples map.
+func renderNamedExamples(sb *strings.Builder, examples map[string]*Example, indent string) {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ex := examples[name]
+		if ex == nil {
+			continue
+		}
+		label := name
+		if ex.Summary != "" {
+			label = minifyText(ex.Summary)
+		}
+		var value string
+		switch {
+		case ex.Value != nil:
+			encoded, err := json.Marshal(toJSONValue(ex.Value))
+			if err != nil {
+				encoded = []byte(fmt.Sprintf("%v", ex.Value))
+			}
+			value = string(encoded)
+		case ex.ExternalValue != "":
+			value = "see " + ex.ExternalValue
+		default:
+			value = "(no value)"
+		}
+		sb.WriteString(fmt.Sprintf("%sEXAMPLE (%s): %s\n", indent, label, value))
+	}
+}
+
+// sortedMediaTypes returns the media types of content in sorted order.
+func sortedMediaTypes(content map[string]*MediaType) []string {
+	mediaTypes := make([]string, 0, len(content))
+	for mediaType := range content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	return mediaTypes
+}
+
+// formatDescription collapses the whitespace in a description and truncates
+// it to maxLen characters followed by "...". A zero maxLen means
+// DefaultMaxDescriptionLen; a negative one disables truncation.
+func formatDescription(text string, maxLen int) string {
+	desc := minifyText(text)
+	if maxLen == 0 {
+		maxLen = DefaultMaxDescriptionLen
+	}
+	if maxLen < 0 {
+		return desc
+	}
+	if runes := []rune(desc); len(runes) > maxLen {
+		desc = strings.TrimRight(string(runes[:maxLen]), " ") + "..."
+	}
+	return desc
+}
+
+// wrapText wraps text at word boundaries so that, starting at column on the
+// current line, no line exceeds width columns unless a single word does.
+// Continuation lines start with indent. A width of zero or less returns text
+// unchanged.
+func wrapText(text string, column int, indent string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	var sb strings.Builder
+	for i, word := range strings.Fields(text) {
+		n := utf8.RuneCountInString(word)
+		switch {
+		case i == 0:
+		case column+1+n > width:
+			sb.WriteString("\n" + indent)
+			column = utf8.RuneCountInString(indent)
+		default:
+			sb.WriteString(" ")
+			column++
+		}
+		sb.WriteString(word)
+		column += n
+	}
+	return sb.String()
+}
+
+// schemaSummary names a schema in a few words: its component name when it
+// has one, otherwise its type. Element types of arrays are written in
+// brackets, e.g. "array[Pet]", and formats in angle brackets.
+func schemaSummary(s *Schema) string {
+	switch {
+	case s == nil:
+		return "(unknown)"
+	case s.Name != "":
+		return s.Name
+	case s.Ref != "":
+		return unresolvedNote(s.Ref)
+	case len(s.OneOf) > 0:
+		return alternatives("one of", s.OneOf)
+	case len(s.AnyOf) > 0:
+		return alternatives("any of", s.AnyOf)
+	case s.Type.Base() == "array":
+		return withNull(fmt.Sprintf("array[%s]", schemaSummary(s.Items)), s.nullable())
+	case s.Type != "":
+		return withNull(typeWithFormat(s.Type.Base(), s.Format), s.nullable())
+	case len(s.Properties) > 0:
+		return "object"
+	}
+	return "(unknown)"
+}
+
+// unresolvedNote marks a ref that was left in place because its target does
+// not exist, e.g. "(unresolved: #/components/schemas/Pet)".
+func unresolvedNote(ref string) string {
+	return fmt.Sprintf("(unresolved: %s)", ref)
+}
+
+// withNull marks the rendered type of a nullable schema, e.g. "string
+// (nullable)". A schema whose only type is null renders as "null".
+func withNull(rendered string, nullable bool) string {
+	switch {
+	case !nullable:
+		return rendered
+	case rendered == "":
+		return nullType
+	}
+	return rendered + " (nullable)"
+}
+
+// typeWithFormat appends a format to a type, e.g. "string<date-time>" or
+// "integer<int64>". Angle brackets are reserved for formats; array element
+// types use square brackets.
+func typeWithFormat(typ, format string) string {
+	if format == "" || typ == "" {
+		return typ
+	}
+	return fmt.Sprintf("%s<%s>", typ, format)
+}
+
+// unwrapEnvelope returns the inner schema of an envelope object, one whose
+// only property is named in envelopes, along with the wrapper's property
+// name. It returns nil when s is not an envelope.
+func unwrapEnvelope(s *Schema, envelopes []string) (*Schema, string) {
+	if s == nil || len(s.Properties) != 1 {
+		return nil, ""
+	}
+	if len(envelopes) == 0 {
+		envelopes = DefaultEnvelopeProperties
+	}
+	for name, inner := range s.Properties {
+		for _, envelope := range envelopes {
+			if name == envelope && inner != nil {
+				return inner, name
+			}
+		}
+	}
+	return nil, ""
+}
+
+// sortedResponseCodes returns the status codes of responses in ascending
+// order, with the catch-all "default" response last.
+func sortedResponseCodes(responses map[string]*Response) []string {
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if codes[i] == "default" || codes[j] == "default" {
+			return codes[j] == "default" && codes[i] != "default"
+		}
+		return codes[i] < codes[j]
+	})
+	return codes
+}
+
+// renderVariants expands discriminated oneOf request bodies into one example
+// per variant, labeled with the discriminator value that selects it. Plain
+// oneOf bodies are already listed by name in the content summary.
+func renderVariants(sb *strings.Builder, content map[string]*MediaType) {
+	for _, mediaType := range sortedMediaTypes(content) {
+		mt := content[mediaType]
+		if mt == nil || mt.Schema == nil || len(mt.Schema.OneOf) == 0 {
+			continue
+		}
+		d := mt.Schema.Discriminator
+		if d == nil || d.PropertyName == "" {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("  %s VARIANTS (by %s):\n", mediaType, d.PropertyName))
+		for _, variant := range mt.Schema.OneOf {
+			value := discriminatorValue(d, variant)
+			example := GenerateExampleFor(variant, ExampleRequest)
+			if obj, ok := example.(map[string]interface{}); ok && value != "" {
+				obj[d.PropertyName] = value
+			}
+			encoded, err := json.Marshal(toJSONValue(example))
+			if err != nil {
+				encoded = []byte("(unavailable)")
+			}
+			sb.WriteString(fmt.Sprintf("    when %s=%s: %s\n", d.PropertyName, value, encoded))
+		}
+	}
+}
+
+// formMediaTypes are the content types whose schema properties are sent as
+// individual form fields.
+var formMediaTypes = []string{"multipart/form-data", "application/x-www-form-urlencoded"}
+
+// manyFormFields is the field count above which the form field list is
+// prefixed with its size.
+const manyFormFields = 5
+
+// renderFormFields lists the named fields of a form-encoded request body,
+// marking file uploads, e.g.
+// "FORM FIELDS: file (binary file, required), tags (array[string])".
+// Fields introduced after opts.MaxVersion are left out, as are readOnly
+// fields when opts.OmitReadOnlyInRequests is set.
+func renderFormFields(sb *strings.Builder, content map[string]*MediaType, opts RenderOptions) {
+	var declared []string
+	for _, form := range formMediaTypes {
+		for _, mediaType := range sortedMediaTypes(content) {
+			if baseMediaType(mediaType) == form {
+				declared = append(declared, mediaType)
+			}
+		}
+	}
+	for _, mediaType := range declared {
+		mt := content[mediaType]
+		if mt == nil || mt.Schema == nil || len(mt.Schema.Properties) == 0 {
+			continue
+		}
+		required := make(map[string]bool, len(mt.Schema.Required))
+		for _, name := range mt.Schema.Required {
+			required[name] = true
+		}
+		names := make([]string, 0, len(mt.Schema.Properties))
+		for name, field := range mt.Schema.Properties {
+			if omitProperty(field, opts) {
+				continue
+			}
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+
+		fields := make([]string, 0, len(names))
+		for _, name := range names {
+			field := mt.Schema.Properties[name]
+			label := fieldType(field)
+			if required[name] {
+				label += ", required"
+			}
+			var note string
+			if field != nil {
+				label += accessNote(field) + enumNote(field.Enum)
+				note = sinceNote(field.Since) + deprecationNote(field.Deprecated, field.ReplacedBy)
+			}
+			fields = append(fields, fmt.Sprintf("%s (%s)%s", name, label, note))
+		}
+
+		header := "FORM FIELDS"
+		if len(fields) > manyFormFields {
+			header = fmt.Sprintf("FORM FIELDS (%d)", len(fields))
+		}
+		sb.WriteString(fmt.Sprintf("  %s %s: %s\n", mediaType, header, strings.Join(fields, ", ")))
+	}
+}
+
+// fieldType describes the type of a form field, spelling out element types
+// of arrays and marking binary uploads as files.
+func fieldType(s *Schema) string {
+	switch {
+	case s == nil:
+		return "(unknown)"
+	case s.Format == "binary" || s.Type == "file":
+		return "binary file"
+	case s.Type.Base() == "array":
+		return withNull(fmt.Sprintf("array[%s]", fieldType(s.Items)), s.nullable())
+	case s.Type != "":
+		return withNull(typeWithFormat(s.Type.Base(), s.Format), s.nullable())
+	case s.Name != "":
+		return s.Name
+	}
+	return "(unknown)"
+}
+
+// alternatives lists the variants of a oneOf or anyOf schema after label,
+// e.g. "one of: Cat | Dog". oneOf means exactly one variant matches, anyOf
+// that one or more may.
+func alternatives(label string, variants []*Schema) string {
+	names := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		names = append(names, variantName(variant))
+	}
+	return label + ": " + strings.Join(names, " | ")
+}
+
+// variantName returns a short label for a oneOf or anyOf variant: its
+// component name, or a description of its type for an inline schema.
+func variantName(s *Schema) string {
+	switch {
+	case s == nil:
+		return "(unknown)"
+	case s.Name != "":
+		return s.Name
+	case s.Ref != "":
+		return unresolvedNote(s.Ref)
+	case s.Type != "":
+		return schemaSummary(s)
+	}
+	return "(inline)"
+}
+
+// =====================================================
+// Existing Functions for Reference Resolution
+// =====================================================
+
+// ResolveOptions customizes ResolveReferencesWithOptions.
+type ResolveOptions struct {
+	// Resolver, when non-nil, is consulted for schema refs that the built-in
+	// lookup in doc.Components cannot satisfy, such as refs into a registry
+	// or a custom scheme. Components always take precedence; the resolver
+	// only sees the remainder. Returning a nil schema and nil error reports
+	// the ref as unresolved.
+	Resolver func(ref string) (*Schema, error)
+	// BaseDir is the directory that refs into other files, such as
+	// "./schemas/pet.yaml#/Pet", are resolved against. It defaults to the
+	// directory of doc.SourcePath, or the working directory.
+	BaseDir string
+	// FS, when set, is where refs into other files are read from, with
+	// BaseDir a slash-separated directory inside it. It defaults to the zip
+	// bundle the document was loaded from.
+	FS fs.FS
+	// Cache, when non-nil, memoizes the schemas Resolver returns by ref, so
+	// a long-running service that resolves many specs against the same
+	// registry looks each ref up once. Only Resolver results are cached;
+	// refs into doc.Components or other files are looked up as usual. It
+	// may be shared between goroutines.
+	Cache *SchemaCache
+
+	// external loads and caches the files that refs point into; it is set up
+	// by ResolveReferencesWithOptions.
+	external *externalRefs
+	// unresolved, when set by ResolveReferencesLenient, collects the refs
+	// that cannot be resolved instead of failing on the first.
+	unresolved *[]string
+}
+
+// SchemaCache remembers the schema ResolveOptions.Resolver returned for each
+// ref. The zero value is not usable; create one with NewSchemaCache.
+type SchemaCache struct {
+	mu      sync.Mutex
+	schemas map[string]*Schema
+}
+
+// NewSchemaCache returns an empty SchemaCache.
+func NewSchemaCache() *SchemaCache {
+	return &SchemaCache{schemas: make(map[string]*Schema)}
+}
+
+func (c *SchemaCache) get(ref string) (*Schema, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.schemas[ref]
+	return s, ok
+}
+
+func (c *SchemaCache) put(ref string, s *Schema) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schemas[ref] = s
+}
+
+// ErrCircularReference is returned, wrapped with the chain of schema names,
+// e.g. "circular reference detected: A -> B -> A", when a schema that is
+// nothing but a $ref leads through other such schemas back to itself, so the
+// chain never reaches a real schema. Schemas that refer to each other, or to
+// themselves, through properties, items or composition are valid recursive
+// types, not an error: they resolve to pointers forming a cycle, which the
+// renderers stop at.
+var ErrCircularReference = errors.New("circular reference detected")
+
+// unresolvedError reports a ref whose target does not exist.
+type unresolvedError struct {
+	kind string
+	ref  string
+}
+
+func (e *unresolvedError) Error() string {
+	return fmt.Sprintf("unresolved %s reference: %s", e.kind, e.ref)
+}
+
+// tolerate returns err unchanged, unless it is an unresolved ref and opts
+// collects those, in which case the ref is recorded and nil is returned.
+func (opts ResolveOptions) tolerate(err error) error {
+	var unresolved *unresolvedError
+	if opts.unresolved == nil || !errors.As(err, &unresolved) {
+		return err
+	}
+	*opts.unresolved = append(*opts.unresolved, unresolved.Error())
+	return nil
+}
+
+// ResolveReferences replaces $ref fields in the document with direct pointers to Components.
+// A parameter $ref may carry sibling fields such as description or required;
+// those override the referenced component's values for that endpoint only.
+// Refs into other files, e.g. "./schemas/pet.yaml#/Pet", are loaded relative
+// to the spec's directory. Path items that are a $ref, to
+// components.pathItems or another file, have their operations spliced in.
+//
+// A chain of schemas that are only a $ref and loops back on itself fails
+// with ErrCircularReference; recursive schemas are resolved as cycles of
+// pointers instead.
+//
+// Resolution is idempotent: calling it again on a resolved document finds
+// nothing left to replace and leaves the document unchanged.
+func ResolveReferences(doc *APIDocument) error {
+	return ResolveReferencesWithOptions(doc, ResolveOptions{})
+}
+
+// ResolveReferencesLenient is ResolveReferencesWithOptions for imperfect
+// specs: a ref whose target does not exist is left in place, rendered as
+// "(unresolved: #/...)", and listed in the result instead of failing the
+// whole run. Other problems, such as circular refs or unreadable files,
+// are still returned as errors.
+func ResolveReferencesLenient(doc *APIDocument, opts ResolveOptions) ([]string, error) {
+	var unresolved []string
+	opts.unresolved = &unresolved
+	err := ResolveReferencesWithOptions(doc, opts)
+
+	seen := make(map[string]bool, len(unresolved))
+	unique := unresolved[:0]
+	for _, msg := range unresolved {
+		if !seen[msg] {
+			seen[msg] = true
+			unique = append(unique, msg)
+		}
+	}
+	return unique, err
+}
+
+// ResolveReferencesWithOptions is ResolveReferences with a custom configuration.
+func ResolveReferencesWithOptions(doc *APIDocument, opts ResolveOptions) error {
+	if doc.Components == nil && opts.Resolver == nil && len(doc.pathRefs) == 0 && !hasExternalRefs(doc) {
+		return nil
+	}
+	if opts.FS == nil {
+		opts.FS = doc.sourceFS
+	}
+	if opts.BaseDir == "" && doc.SourcePath != "" {
+		opts.BaseDir = filepath.Dir(doc.SourcePath)
+		if opts.FS != nil {
+			opts.BaseDir = path.Dir(doc.SourcePath)
+		}
+	}
+	opts.external = newExternalRefs(opts.BaseDir, opts.FS)
+	components := doc.Components
+	if components == nil {
+		components = &Components{}
+	}
+
+	if err := resolvePathRefs(doc, components, opts); err != nil {
+		return err
+	}
+
+	// Remember each component schema's name so it survives resolution.
+	for name, schema := range components.Schemas {
+		if schema != nil && schema.Name == "" {
+			schema.Name = name
+		}
+	}
+
+	for i := range doc.Endpoints {
+		ep := &doc.Endpoints[i]
+
+		// Resolve parameters.
+		for j, param := range ep.Parameters {
+			if param == nil {
+				continue
 			}
-			if err := resolveSchema(&param.Schema, doc); err != nil {
+			if param.Ref != "" {
+				refName := extractNameFromRef(param.Ref, "parameters")
+				resolved, ok := components.Parameters[refName]
+				if !ok && isExternalRef(param.Ref) {
+					resolved = &Parameter{}
+					if err := opts.external.decode(param.Ref, resolved); err != nil {
+						return err
+					}
+					ok = true
+				}
+				if ok {
+					param = overrideParameter(resolved, param)
+					ep.Parameters[j] = param
+				} else if err := opts.tolerate(&unresolvedError{"parameter", param.Ref}); err != nil {
+					return err
+				}
+			}
+			if err := resolveSchema(&param.Schema, doc, opts); err != nil {
 				return err
 			}
 		}
@@ -370,19 +2794,29 @@ func ResolveReferences(doc *APIDocument) error {
 		if ep.RequestBody != nil {
 			if ep.RequestBody.Ref != "" {
 				refName := extractNameFromRef(ep.RequestBody.Ref, "requestBodies")
-				if resolved, ok := doc.Components.RequestBodies[refName]; ok {
+				resolved, ok := components.RequestBodies[refName]
+				if !ok && isExternalRef(ep.RequestBody.Ref) {
+					resolved = &RequestBody{}
+					if err := opts.external.decode(ep.RequestBody.Ref, resolved); err != nil {
+						return err
+					}
+					ok = true
+				}
+				if ok {
 					ep.RequestBody = resolved
-				} else {
-					errMsg := fmt.Sprintf("unresolved requestBody reference: %s", ep.RequestBody.Ref)
-					return fmt.Errorf(errMsg)
+				} else if err := opts.tolerate(&unresolvedError{"requestBody", ep.RequestBody.Ref}); err != nil {
+					return err
 				}
 			}
 			for _, mt := range ep.RequestBody.Content {
 				if mt != nil && mt.Schema != nil {
-					if err := resolveSchema(&mt.Schema, doc); err != nil {
+					if err := resolveSchema(&mt.Schema, doc, opts); err != nil {
 						return err
 					}
 				}
+				if err := resolveExamples(mt, components, opts); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -393,47 +2827,355 @@ func ResolveReferences(doc *APIDocument) error {
 			}
 			if resp.Ref != "" {
 				refName := extractNameFromRef(resp.Ref, "responses")
-				if resolved, ok := doc.Components.Responses[refName]; ok {
+				resolved, ok := components.Responses[refName]
+				if !ok && isExternalRef(resp.Ref) {
+					resolved = &Response{}
+					if err := opts.external.decode(resp.Ref, resolved); err != nil {
+						return err
+					}
+					ok = true
+				}
+				if ok {
 					ep.Responses[code] = resolved
-				} else {
-					errMsg := fmt.Sprintf("unresolved response reference: %s", resp.Ref)
-					return fmt.Errorf(errMsg)
+					resp = resolved
+				} else if err := opts.tolerate(&unresolvedError{"response", resp.Ref}); err != nil {
+					return err
 				}
 			}
+			if err := resolveHeaders(resp.Headers, doc, components, opts); err != nil {
+				return err
+			}
 			for _, mt := range resp.Content {
 				if mt != nil && mt.Schema != nil {
-					if err := resolveSchema(&mt.Schema, doc); err != nil {
+					if err := resolveSchema(&mt.Schema, doc, opts); err != nil {
 						return err
 					}
 				}
+				if err := resolveExamples(mt, components, opts); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Resolve the refs nested inside component schemas too, including those
+	// no endpoint uses. An alias component, one that is only a $ref, is left
+	// in place; its target is resolved under its own name.
+	names := make([]string, 0, len(components.Schemas))
+	for name := range components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schema := components.Schemas[name]
+		if err := resolveSchema(&schema, doc, opts); err != nil {
+			return fmt.Errorf("resolving component schema %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// resolveHeaders replaces referenced response headers with the component
+// headers they point at and resolves their schemas.
+func resolveHeaders(headers map[string]*Parameter, doc *APIDocument, components *Components, opts ResolveOptions) error {
+	for name, header := range headers {
+		if header == nil {
+			continue
+		}
+		if header.Ref != "" {
+			resolved, ok := components.Headers[extractNameFromRef(header.Ref, "headers")]
+			if !ok {
+				if err := opts.tolerate(&unresolvedError{"header", header.Ref}); err != nil {
+					return err
+				}
+				continue
+			}
+			header = resolved
+			headers[name] = header
+		}
+		if err := resolveSchema(&header.Schema, doc, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolvePathRefs splices the operations of each path item given as a $ref
+// into doc.Endpoints, after the endpoints of the paths sorting before it.
+// Operations declared next to the $ref take precedence over the referenced
+// ones.
+func resolvePathRefs(doc *APIDocument, components *Components, opts ResolveOptions) error {
+	for _, pr := range doc.pathRefs {
+		item, err := lookupPathItem(pr.ref, components, opts)
+		if err != nil {
+			if err := opts.tolerate(err); err != nil {
+				return fmt.Errorf("path %s: %w", pr.path, err)
+			}
+			continue
+		}
+		declared := make(map[string]bool)
+		at := len(doc.Endpoints)
+		for i, ep := range doc.Endpoints {
+			if ep.Path == pr.path {
+				declared[ep.Method] = true
+			}
+			if ep.Path > pr.path && at == len(doc.Endpoints) {
+				at = i
+			}
+		}
+		var spliced []Endpoint
+		for _, ep := range appendPathEndpoints(nil, pr.path, *item, pr.security) {
+			if !declared[ep.Method] {
+				spliced = append(spliced, ep)
 			}
 		}
+		doc.Endpoints = append(doc.Endpoints[:at], append(spliced, doc.Endpoints[at:]...)...)
 	}
+	doc.pathRefs = nil
 	return nil
 }
 
-// resolveSchema replaces a Schema reference with a pointer to the component schema.
-func resolveSchema(s **Schema, doc *APIDocument) error {
+// lookupPathItem finds the path item a ref points at, among the document's
+// components or in another file.
+func lookupPathItem(ref string, components *Components, opts ResolveOptions) (*PathItem, error) {
+	if item, ok := components.PathItems[extractNameFromRef(ref, "pathItems")]; ok && item != nil {
+		return item, nil
+	}
+	if isExternalRef(ref) {
+		item := &PathItem{}
+		if err := opts.external.decode(ref, item); err != nil {
+			return nil, err
+		}
+		return item, nil
+	}
+	return nil, &unresolvedError{"path item", ref}
+}
+
+// resolveSchema replaces a Schema reference with a pointer to the component
+// schema, and does the same for the references nested in its properties,
+// array items, oneOf and anyOf variants and allOf members. allOf members are then
+// merged into the schema, see mergeAllOf. Self-referencing schemas are
+// visited once: a property that refers back to its own schema is a valid
+// recursive type.
+func resolveSchema(s **Schema, doc *APIDocument, opts ResolveOptions) error {
+	return resolveSchemaTree(s, doc, opts, make(map[*Schema]bool))
+}
+
+func resolveSchemaTree(s **Schema, doc *APIDocument, opts ResolveOptions, visited map[*Schema]bool) error {
 	if *s == nil {
 		return nil
 	}
 	if (*s).Ref != "" {
-		refName := extractNameFromRef((*s).Ref, "schemas")
-		if resolved, ok := doc.Components.Schemas[refName]; ok {
-			*s = resolved
-		} else {
-			errMsg := fmt.Sprintf("unresolved schema reference: %s", (*s).Ref)
-			return fmt.Errorf(errMsg)
+		resolved, err := followSchemaRef((*s).Ref, doc, opts)
+		if err != nil {
+			return opts.tolerate(err)
+		}
+		*s = resolved
+	}
+	if visited[*s] {
+		return nil
+	}
+	visited[*s] = true
+
+	for name := range (*s).Properties {
+		prop := (*s).Properties[name]
+		if err := resolveSchemaTree(&prop, doc, opts, visited); err != nil {
+			return err
+		}
+		(*s).Properties[name] = prop
+	}
+	if err := resolveSchemaTree(&(*s).Items, doc, opts, visited); err != nil {
+		return err
+	}
+	for i := range (*s).OneOf {
+		if err := resolveSchemaTree(&(*s).OneOf[i], doc, opts, visited); err != nil {
+			return err
+		}
+	}
+	for i := range (*s).AnyOf {
+		if err := resolveSchemaTree(&(*s).AnyOf[i], doc, opts, visited); err != nil {
+			return err
+		}
+	}
+	for i := range (*s).AllOf {
+		if err := resolveSchemaTree(&(*s).AllOf[i], doc, opts, visited); err != nil {
+			return err
+		}
+	}
+	mergeAllOf(*s)
+	return nil
+}
+
+// mergeAllOf folds the resolved allOf members of s into s itself, so that a
+// composed schema renders as one object. Properties are merged after the
+// schema's own, in allOf order, so when a property is defined more than once
+// the last definition wins. Required lists are combined, and the type and
+// discriminator are taken from the first member that sets them if s has
+// none of its own.
+func mergeAllOf(s *Schema) {
+	if len(s.AllOf) == 0 {
+		return
+	}
+	props := make(map[string]*Schema, len(s.Properties))
+	for name, prop := range s.Properties {
+		props[name] = prop
+	}
+	required := append([]string(nil), s.Required...)
+	seen := make(map[string]bool, len(required))
+	for _, name := range required {
+		seen[name] = true
+	}
+	for _, member := range s.AllOf {
+		if member == nil {
+			continue
+		}
+		for name, prop := range member.Properties {
+			props[name] = prop
+		}
+		for _, name := range member.Required {
+			if !seen[name] {
+				seen[name] = true
+				required = append(required, name)
+			}
+		}
+		if s.Type == "" {
+			s.Type = member.Type
+		}
+		if s.Discriminator == nil {
+			s.Discriminator = member.Discriminator
+		}
+	}
+	if s.Type == "" && len(props) > 0 {
+		s.Type = "object"
+	}
+	s.Properties = props
+	s.Required = required
+	s.AllOf = nil
+}
+
+// followSchemaRef looks up the schema ref points at, following chains of
+// schemas that are themselves only a $ref. A chain that comes back to a ref
+// it already passed can never reach a real schema and is reported as
+// ErrCircularReference.
+func followSchemaRef(ref string, doc *APIDocument, opts ResolveOptions) (*Schema, error) {
+	var chain []string
+	seen := make(map[string]bool)
+	for {
+		chain = append(chain, refBaseName(ref))
+		if seen[ref] {
+			return nil, fmt.Errorf("%w: %s", ErrCircularReference, strings.Join(chain, " -> "))
+		}
+		seen[ref] = true
+		resolved, err := lookupSchema(ref, doc, opts)
+		if err != nil {
+			return nil, err
+		}
+		if resolved.Ref == "" {
+			return resolved, nil
+		}
+		ref = resolved.Ref
+	}
+}
+
+// overrideParameter returns a copy of the component parameter resolved with
+// the non-empty sibling fields of the referencing parameter ref applied on
+// top, so an endpoint can customize the description, required flag,
+// deprecation or default of a shared parameter.
+func overrideParameter(resolved, ref *Parameter) *Parameter {
+	merged := *resolved
+	if ref.Description != "" {
+		merged.Description = ref.Description
+	}
+	if ref.Required {
+		merged.Required = true
+	}
+	if ref.Deprecated {
+		merged.Deprecated = true
+	}
+	if ref.ReplacedBy != "" {
+		merged.ReplacedBy = ref.ReplacedBy
+	}
+	if ref.Default != nil {
+		merged.Default = ref.Default
+	}
+	return &merged
+}
+
+// resolveExamples replaces referenced named examples of a media type with
+// the component examples they point at.
+func resolveExamples(mt *MediaType, components *Components, opts ResolveOptions) error {
+	if mt == nil {
+		return nil
+	}
+	for name, ex := range mt.Examples {
+		if ex == nil || ex.Ref == "" {
+			continue
 		}
+		resolved, ok := components.Examples[extractNameFromRef(ex.Ref, "examples")]
+		if !ok {
+			if err := opts.tolerate(&unresolvedError{"example", ex.Ref}); err != nil {
+				return err
+			}
+			continue
+		}
+		mt.Examples[name] = resolved
 	}
 	return nil
 }
 
+// lookupSchema finds the schema a ref points at, first among the document's
+// components, then in another file for refs with a file part, and then
+// through the caller's custom resolver, or its cache of earlier answers.
+func lookupSchema(ref string, doc *APIDocument, opts ResolveOptions) (*Schema, error) {
+	if doc.Components != nil {
+		if resolved, ok := doc.Components.Schemas[extractNameFromRef(ref, "schemas")]; ok {
+			return resolved, nil
+		}
+	}
+	if isExternalRef(ref) && opts.external != nil {
+		return opts.external.schema(ref)
+	}
+	if opts.Resolver != nil {
+		if opts.Cache != nil {
+			if resolved, ok := opts.Cache.get(ref); ok {
+				return resolved, nil
+			}
+		}
+		resolved, err := opts.Resolver(ref)
+		if err != nil {
+			return nil, fmt.Errorf("resolving schema reference %s: %w", ref, err)
+		}
+		if resolved != nil {
+			if opts.Cache != nil {
+				opts.Cache.put(ref, resolved)
+			}
+			return resolved, nil
+		}
+	}
+	return nil, &unresolvedError{"schema", ref}
+}
+
 // extractNameFromRef extracts the component name from a $ref string.
 // E.g. "#/components/schemas/Pet" with componentType "schemas" returns "Pet".
+// Swagger 2.0 refs such as "#/definitions/Pet" or "#/parameters/Limit" are
+// understood as well.
 func extractNameFromRef(ref, componentType string) string {
 	prefix := "#/components/" + componentType + "/"
-	return strings.TrimPrefix(ref, prefix)
+	if strings.HasPrefix(ref, prefix) {
+		return strings.TrimPrefix(ref, prefix)
+	}
+	if swaggerPrefix, ok := swaggerRefPrefixes[componentType]; ok {
+		return strings.TrimPrefix(ref, swaggerPrefix)
+	}
+	return ref
+}
+
+// swaggerRefPrefixes maps component types to the top-level sections Swagger
+// 2.0 uses for the same reusable objects.
+var swaggerRefPrefixes = map[string]string{
+	"schemas":    "#/definitions/",
+	"parameters": "#/parameters/",
+	"responses":  "#/responses/",
 }
 
 // snippet is a helper function to safely print the first n bytes of a file.
@@ -442,4 +3184,4 @@ func snippet(data []byte, n int) string {
 		return string(data)
 	}
 	return string(data[:n]) + "..."
-}
\ No newline at end of file
+}
@@ -0,0 +1,704 @@
+package openapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadTestSpec loads spec, given inline as YAML or JSON, and resolves its
+// references.
+func loadTestSpec(t *testing.T, spec string) *APIDocument {
+	t.Helper()
+	doc, err := LoadAPISpecReader(strings.NewReader(spec))
+	if err != nil {
+		t.Fatalf("loading spec: %v", err)
+	}
+	if err := ResolveReferences(doc); err != nil {
+		t.Fatalf("resolving references: %v", err)
+	}
+	return doc
+}
+
+// benchmarkJSONSpec builds an OpenAPI 3 JSON spec with n paths, each with a
+// GET and a POST taking a small object body.
+func benchmarkJSONSpec(n int) []byte {
+	paths := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		paths[fmt.Sprintf("/items%d/{id}", i)] = map[string]interface{}{
+			"parameters": []interface{}{
+				map[string]interface{}{"name": "id", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}},
+			},
+			"get": map[string]interface{}{
+				"summary":   fmt.Sprintf("Get item %d", i),
+				"responses": map[string]interface{}{"200": map[string]interface{}{"description": "ok"}},
+			},
+			"post": map[string]interface{}{
+				"summary": fmt.Sprintf("Update item %d", i),
+				"requestBody": map[string]interface{}{
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"name":  map[string]interface{}{"type": "string"},
+									"count": map[string]interface{}{"type": "integer"},
+								},
+							},
+						},
+					},
+				},
+				"responses": map[string]interface{}{"204": map[string]interface{}{"description": "updated"}},
+			},
+		}
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"openapi": "3.0.0",
+		"info":    map[string]interface{}{"title": "Bench", "version": "1"},
+		"paths":   paths,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func BenchmarkParseJSONSpec(b *testing.B) {
+	data := benchmarkJSONSpec(200)
+	for _, bc := range []struct {
+		name string
+		opts LoadOptions
+	}{
+		{"Default", LoadOptions{}},
+		{"AssumeJSON", LoadOptions{AssumeJSON: true}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := parseAPISpec(data, bc.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderMaxVersionHidesNewerProperties(t *testing.T) {
+	doc := loadTestSpec(t, `
+title: Pets
+endpoints:
+  - path: /pets
+    method: post
+    requestBody:
+      content:
+        application/json:
+          schema:
+            type: object
+            properties:
+              name: {type: string, x-since: "1.0"}
+              nickname: {type: string, x-since: "3.0"}
+        multipart/form-data:
+          schema:
+            type: object
+            properties:
+              photo: {type: string, format: binary, x-since: "3.0"}
+`)
+	full := RenderTextWithOptions(doc, RenderOptions{})
+	if !strings.Contains(full, "- nickname (string) (since v3.0)") {
+		t.Errorf("without MaxVersion, want nickname listed with its x-since, got:\n%s", full)
+	}
+
+	limited := RenderTextWithOptions(doc, RenderOptions{MaxVersion: "2.3"})
+	if !strings.Contains(limited, "- name (string) (since v1.0)") {
+		t.Errorf("with MaxVersion 2.3, want name kept, got:\n%s", limited)
+	}
+	for _, field := range []string{"nickname", "photo"} {
+		if strings.Contains(limited, field) {
+			t.Errorf("with MaxVersion 2.3, want %s left out, got:\n%s", field, limited)
+		}
+	}
+}
+
+func TestLoadOpenAPI3(t *testing.T) {
+	doc := loadTestSpec(t, `
+openapi: 3.0.3
+info: {title: Pets, version: "1.0"}
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: limit, in: query, schema: {type: integer}}
+      responses: {'200': {description: ok}}
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {type: object}
+      responses: {'201': {description: created}}
+  /pets/{id}:
+    delete:
+      responses: {'204': {description: deleted}}
+`)
+	if len(doc.Endpoints) != 3 {
+		t.Fatalf("got %d endpoints, want 3", len(doc.Endpoints))
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "https://api.example.com/v1" {
+		t.Errorf("got servers %+v, want https://api.example.com/v1", doc.Servers)
+	}
+	for _, ep := range doc.Endpoints {
+		if ep.Method == "POST" && ep.RequestBody == nil {
+			t.Errorf("POST %s lost its request body", ep.Path)
+		}
+		if ep.Method == "GET" && (len(ep.Parameters) != 1 || ep.Parameters[0].Name != "limit") {
+			t.Errorf("GET %s has parameters %+v, want limit", ep.Path, ep.Parameters)
+		}
+	}
+}
+
+func TestResolveReferencesCircularAlias(t *testing.T) {
+	doc, err := LoadAPISpecReader(strings.NewReader(`
+openapi: 3.0.0
+info: {title: Loop, version: "1"}
+paths:
+  /a:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/A'}
+components:
+  schemas:
+    A: {$ref: '#/components/schemas/B'}
+    B: {$ref: '#/components/schemas/A'}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ResolveReferences(doc)
+	if !errors.Is(err, ErrCircularReference) {
+		t.Fatalf("got error %v, want ErrCircularReference", err)
+	}
+	if !strings.Contains(err.Error(), "A -> B -> A") {
+		t.Errorf("error %q does not name the chain A -> B -> A", err)
+	}
+}
+
+func TestResolveReferencesMutualRecursion(t *testing.T) {
+	doc := loadTestSpec(t, `
+openapi: 3.0.0
+info: {title: People, version: "1"}
+paths:
+  /people:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Person'}
+components:
+  schemas:
+    Person:
+      type: object
+      properties:
+        employer: {$ref: '#/components/schemas/Company'}
+    Company:
+      type: object
+      properties:
+        owner: {$ref: '#/components/schemas/Person'}
+`)
+	person := doc.Endpoints[0].Responses["200"].Content["application/json"].Schema
+	company := person.Properties["employer"]
+	if company == nil || company.Name != "Company" {
+		t.Fatalf("employer resolved to %+v, want Company", company)
+	}
+	if company.Properties["owner"] != person {
+		t.Errorf("Company.owner does not point back at Person")
+	}
+}
+
+func TestRenderTextDeterministic(t *testing.T) {
+	const spec = `
+swagger: "2.0"
+info: {title: Pets, version: "1"}
+paths:
+  /pets/{id}:
+    delete: {responses: {'204': {description: deleted}}}
+    get: {responses: {'200': {description: ok}}}
+    put: {responses: {'200': {description: ok}}}
+  /pets:
+    post: {responses: {'201': {description: created}}}
+    get: {responses: {'200': {description: ok}}}
+  /owners:
+    patch: {responses: {'200': {description: ok}}}
+    options: {responses: {'200': {description: ok}}}
+`
+	first := RenderText(loadTestSpec(t, spec))
+	for i := 0; i < 10; i++ {
+		if again := RenderText(loadTestSpec(t, spec)); again != first {
+			t.Fatalf("render %d differs from the first:\n%s\nfirst:\n%s", i+2, again, first)
+		}
+	}
+
+	var order []string
+	for _, line := range strings.Split(first, "\n") {
+		if strings.HasPrefix(line, "ENDPOINT: ") {
+			order = append(order, strings.TrimPrefix(line, "ENDPOINT: "))
+		}
+	}
+	want := []string{
+		"PATCH /owners", "OPTIONS /owners",
+		"GET /pets", "POST /pets",
+		"GET /pets/{id}", "PUT /pets/{id}", "DELETE /pets/{id}",
+	}
+	if strings.Join(order, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got endpoint order %v, want %v", order, want)
+	}
+}
+
+func TestLoadMultiDocumentYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "split.yaml")
+	spec := `swagger: "2.0"
+info: {title: Pets, version: "1"}
+paths:
+  /pets:
+    get: {responses: {'200': {description: ok}}}
+---
+paths:
+  /owners:
+    get: {responses: {'200': {description: ok}}}
+`
+	if err := os.WriteFile(path, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadAPISpec(path)
+	if err == nil {
+		t.Fatal("loading a two-document file succeeded, want an error instead of dropping /owners")
+	}
+	if !strings.Contains(err.Error(), "2 YAML documents") {
+		t.Errorf("error %q does not explain that the file holds 2 YAML documents", err)
+	}
+}
+
+func TestResolveReferencesNestedComponents(t *testing.T) {
+	doc := loadTestSpec(t, `
+openapi: 3.0.0
+info: {title: Shop, version: "1"}
+paths:
+  /orders/{id}:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Order'}
+components:
+  schemas:
+    Order:
+      type: object
+      properties:
+        customer: {$ref: '#/components/schemas/Customer'}
+    Customer:
+      type: object
+      properties:
+        address: {$ref: '#/components/schemas/Address'}
+        orders: {type: array, items: {$ref: '#/components/schemas/Order'}}
+    Address:
+      type: object
+      properties:
+        street: {type: string}
+`)
+	order := doc.Endpoints[0].Responses["200"].Content["application/json"].Schema
+	customer := order.Properties["customer"]
+	if customer == nil || customer.Ref != "" {
+		t.Fatalf("Order.customer is %+v, want the resolved Customer", customer)
+	}
+	address := customer.Properties["address"]
+	if address == nil || address.Ref != "" || address.Properties["street"] == nil {
+		t.Fatalf("Customer.address is %+v, want the resolved Address with its street", address)
+	}
+	if customer.Properties["orders"].Items != order {
+		t.Errorf("Customer.orders items do not point back at Order")
+	}
+	if doc.Components.Schemas["Customer"].Properties["address"] != address {
+		t.Errorf("the Customer component was not resolved in place")
+	}
+}
+
+func TestRenderTruncatesLongDescriptions(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	doc := &APIDocument{
+		Title: "Pets",
+		Endpoints: []Endpoint{
+			{Method: "GET", Path: "/long", Description: long},
+			{Method: "GET", Path: "/short", Description: "Lists pets."},
+		},
+	}
+	text := RenderTextWithOptions(doc, RenderOptions{MaxDescriptionLen: 20})
+	if want := "DESCRIPTION: word word word word...\n"; !strings.Contains(text, want) {
+		t.Errorf("want the long description cut at 20 characters as %q, got:\n%s", want, text)
+	}
+	if !strings.Contains(text, "DESCRIPTION: Lists pets.\n") {
+		t.Errorf("want the short description untouched, got:\n%s", text)
+	}
+
+	if text := RenderTextWithOptions(doc, RenderOptions{MaxDescriptionLen: -1}); !strings.Contains(text, strings.TrimSpace(long)) {
+		t.Errorf("want the full description with truncation disabled, got:\n%s", text)
+	}
+}
+
+func TestLoadGzippedSpec(t *testing.T) {
+	spec := []byte(`{
+  "swagger": "2.0",
+  "info": {"title": "Pets", "version": "1"},
+  "paths": {
+    "/pets": {"get": {"responses": {"200": {"description": "ok"}}}},
+    "/pets/{id}": {"delete": {"responses": {"204": {"description": "deleted"}}}}
+  }
+}`)
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "swagger.json")
+	if err := os.WriteFile(plainPath, spec, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gzPath := filepath.Join(dir, "swagger.json.gz")
+	if err := os.WriteFile(gzPath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := LoadAPISpec(plainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipped, err := LoadAPISpec(gzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zipped.Endpoints) != 2 {
+		t.Fatalf("got %d endpoints from the gzipped spec, want 2", len(zipped.Endpoints))
+	}
+	if !reflect.DeepEqual(plain.Endpoints, zipped.Endpoints) {
+		t.Errorf("gzipped endpoints %+v differ from plain %+v", zipped.Endpoints, plain.Endpoints)
+	}
+
+	corruptPath := filepath.Join(dir, "corrupt.json.gz")
+	if err := os.WriteFile(corruptPath, buf.Bytes()[:buf.Len()/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAPISpec(corruptPath); err == nil || !strings.Contains(err.Error(), "corrupt gzip stream") {
+		t.Errorf("got error %v loading a truncated gzip file, want a corrupt gzip stream error", err)
+	}
+}
+
+func TestParameterTypeArrays(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		p    *Parameter
+		want string
+	}{
+		{"swagger items", &Parameter{Type: "array", Items: &Schema{Type: "string"}}, "array[string]"},
+		{"schema items", &Parameter{Schema: &Schema{Type: "array", Items: &Schema{Type: "integer", Format: "int64"}}}, "array[integer<int64>]"},
+		{"format", &Parameter{Type: "integer", Format: "int32"}, "integer<int32>"},
+	} {
+		if got := parameterType(tc.p); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSchemaTypeForms(t *testing.T) {
+	for _, tc := range []struct {
+		name, spec string
+	}{
+		{"yaml", `
+openapi: 3.1.0
+info: {title: Pets, version: "1"}
+paths:
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name: {type: string}
+                nickname: {type: [string, "null"]}
+      responses: {'201': {description: created}}
+`},
+		{"json", `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pets", "version": "1"},
+  "paths": {"/pets": {"post": {
+    "requestBody": {"content": {"application/json": {"schema": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "nickname": {"type": ["string", "null"]}
+      }
+    }}}},
+    "responses": {"201": {"description": "created"}}
+  }}}
+}`},
+	} {
+		doc := loadTestSpec(t, tc.spec)
+		props := doc.Endpoints[0].RequestBody.Content["application/json"].Schema.Properties
+
+		name, nickname := props["name"].Type, props["nickname"].Type
+		if name != "string" || name.Nullable() {
+			t.Errorf("%s: scalar type parsed as %q, nullable %v; want string", tc.name, name, name.Nullable())
+		}
+		if nickname != "string|null" || nickname.Base() != "string" || !nickname.Nullable() {
+			t.Errorf("%s: list type parsed as %q; want string|null with base string", tc.name, nickname)
+		}
+
+		text := RenderText(doc)
+		for _, want := range []string{"- name (string)\n", "- nickname (string (nullable))\n"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("%s: want %q in:\n%s", tc.name, want, text)
+			}
+		}
+
+		data, err := json.Marshal(props["nickname"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), `"type":["string","null"]`) {
+			t.Errorf("%s: list type marshaled as %s, want it written back as a list", tc.name, data)
+		}
+	}
+}
+
+func TestResolveReferencesTwice(t *testing.T) {
+	const spec = `
+openapi: 3.0.0
+info: {title: Pets, version: "1"}
+paths:
+  /pets/{id}:
+    parameters:
+      - $ref: '#/components/parameters/PetID'
+    get:
+      responses:
+        '200':
+          $ref: '#/components/responses/Pet'
+components:
+  parameters:
+    PetID: {name: id, in: path, required: true, schema: {type: string}}
+  responses:
+    Pet:
+      description: ok
+      content:
+        application/json:
+          schema: {$ref: '#/components/schemas/Pet'}
+  schemas:
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Named'
+        - type: object
+          properties:
+            owner: {$ref: '#/components/schemas/Owner'}
+    Named:
+      type: object
+      properties:
+        name: {type: string}
+    Owner:
+      type: object
+      properties:
+        pets: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+`
+	doc := loadTestSpec(t, spec)
+	once := RenderText(doc)
+	onceJSON, err := RenderJSON(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ResolveReferences(doc); err != nil {
+		t.Fatalf("resolving a second time: %v", err)
+	}
+	if twice := RenderText(doc); twice != once {
+		t.Errorf("text after resolving twice differs:\n%s\nafter once:\n%s", twice, once)
+	}
+	twiceJSON, err := RenderJSON(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twiceJSON != onceJSON {
+		t.Errorf("JSON after resolving twice differs:\n%s\nafter once:\n%s", twiceJSON, onceJSON)
+	}
+
+	cache := NewSchemaCache()
+	for i := 0; i < 2; i++ {
+		if err := ResolveReferencesWithOptions(doc, ResolveOptions{Cache: cache}); err != nil {
+			t.Fatalf("resolving with a cache, pass %d: %v", i+1, err)
+		}
+	}
+	if again := RenderText(doc); again != once {
+		t.Errorf("text after resolving with a cache differs:\n%s\nafter once:\n%s", again, once)
+	}
+}
+
+func TestResolveReferencesSharedPathItem(t *testing.T) {
+	const spec = `
+openapi: 3.1.0
+info: {title: Pets, version: "1"}
+paths:
+  /animals:
+    $ref: '#/components/pathItems/Pets'
+  /health:
+    get: {responses: {'200': {description: ok}}}
+  /pets:
+    $ref: '#/components/pathItems/Pets'
+components:
+  schemas:
+    Pet: {type: object, properties: {name: {type: string}}}
+  pathItems:
+    Pets:
+      get:
+        summary: List pets
+        responses:
+          '200':
+            description: ok
+            content:
+              application/json:
+                schema: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+      post:
+        summary: Add a pet
+        responses: {'201': {description: created}}
+`
+	doc := loadTestSpec(t, spec)
+	var got []string
+	for _, ep := range doc.Endpoints {
+		got = append(got, ep.Method+" "+ep.Path)
+	}
+	want := []string{"GET /animals", "POST /animals", "GET /health", "GET /pets", "POST /pets"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("got endpoints %v, want %v", got, want)
+	}
+	list := doc.Endpoints[3]
+	if items := list.Responses["200"].Content["application/json"].Schema.Items; items == nil || items.Name != "Pet" {
+		t.Errorf("GET /pets returns %+v, want an array of the resolved Pet", items)
+	}
+
+	missing, err := LoadAPISpecReader(strings.NewReader(strings.Replace(spec, "pathItems/Pets'\n  /health", "pathItems/Nope'\n  /health", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ResolveReferences(missing)
+	if err == nil || !strings.Contains(err.Error(), "path /animals") || !strings.Contains(err.Error(), "#/components/pathItems/Nope") {
+		t.Errorf("got error %v, want one naming /animals and the missing path item", err)
+	}
+}
+
+func TestPathLevelParameters(t *testing.T) {
+	doc := loadTestSpec(t, `
+openapi: 3.0.0
+info: {title: Pets, version: "1"}
+paths:
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, required: true, description: Pet ID, schema: {type: string}}
+      - {name: verbose, in: query, schema: {type: boolean}}
+    get:
+      responses: {'200': {description: ok}}
+    put:
+      parameters:
+        - {name: id, in: path, required: true, description: ID of the pet to replace, schema: {type: integer}}
+      responses: {'200': {description: ok}}
+    delete:
+      responses: {'204': {description: deleted}}
+`)
+	if len(doc.Endpoints) != 3 {
+		t.Fatalf("got %d endpoints, want 3", len(doc.Endpoints))
+	}
+	for _, ep := range doc.Endpoints {
+		var id *Parameter
+		for _, p := range ep.Parameters {
+			if p.In == "path" && p.Name == "id" {
+				if id != nil {
+					t.Errorf("%s %s lists the id parameter twice", ep.Method, ep.Path)
+				}
+				id = p
+			}
+		}
+		if id == nil {
+			t.Errorf("%s %s is missing the shared id parameter", ep.Method, ep.Path)
+			continue
+		}
+		want := "Pet ID"
+		if ep.Method == "PUT" {
+			want = "ID of the pet to replace"
+		}
+		if id.Description != want {
+			t.Errorf("%s %s: got id described as %q, want %q", ep.Method, ep.Path, id.Description, want)
+		}
+		if len(ep.Parameters) != 2 {
+			t.Errorf("%s %s has %d parameters, want id and verbose", ep.Method, ep.Path, len(ep.Parameters))
+		}
+	}
+}
+
+func TestRenderSecurity(t *testing.T) {
+	doc := loadTestSpec(t, `
+openapi: 3.0.0
+info: {title: Pets, version: "1"}
+paths:
+  /pets:
+    get:
+      security:
+        - petstore_auth: [read:pets, write:pets]
+      responses: {'200': {description: ok}}
+  /pets/{id}:
+    delete:
+      security:
+        - petstore_auth: [read:pets]
+        - petstore_auth: [admin]
+        - key: []
+      responses: {'204': {description: deleted}}
+  /health:
+    get:
+      security:
+        - key: []
+      responses: {'200': {description: ok}}
+components:
+  securitySchemes:
+    petstore_auth:
+      type: oauth2
+      flows:
+        implicit:
+          authorizationUrl: https://example.com/oauth
+          scopes: {read:pets: read, write:pets: write, admin: everything}
+    key: {type: apiKey, in: header, name: X-API-Key}
+`)
+	text := RenderText(doc)
+	for _, want := range []string{
+		"ENDPOINT: GET /pets\n",
+		"SECURITY: requires OAuth2 (petstore_auth, scopes: read:pets, write:pets)\n",
+		"SECURITY: requires OAuth2 (petstore_auth, scopes: read:pets) or OAuth2 (petstore_auth, scopes: admin) or apiKey (header: X-API-Key)\n",
+		"SECURITY: requires apiKey (header: X-API-Key)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("want %q in:\n%s", want, text)
+		}
+	}
+}
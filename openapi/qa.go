@@ -0,0 +1,186 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// =====================================================
+// Question/Answer Rendering
+// =====================================================
+
+// QAPair is a synthetic question about the API with its answer, small and
+// self-contained enough to be embedded as a single retrieval chunk.
+type QAPair struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	// Endpoint identifies the operation the pair is about, e.g. "POST /pets".
+	Endpoint string `json:"endpoint"`
+}
+
+// RenderQA derives question/answer pairs from each endpoint: how to call it,
+// built from its summary, method, path, required parameters and body fields,
+// and what it returns.
+func RenderQA(doc *APIDocument) []QAPair {
+	var pairs []QAPair
+	for _, ep := range doc.Endpoints {
+		method := strings.ToUpper(ep.Method)
+		endpoint := fmt.Sprintf("%s %s", method, ep.Path)
+
+		question := fmt.Sprintf("How do I call %s?", endpoint)
+		if summary := strings.TrimRight(minifyText(ep.Summary), "."); summary != "" {
+			question = fmt.Sprintf("How do I %s?", lowerFirst(summary))
+		}
+		pairs = append(pairs, QAPair{
+			Question: question,
+			Answer:   callAnswer(ep, endpoint),
+			Endpoint: endpoint,
+		})
+
+		if len(ep.Responses) > 0 {
+			pairs = append(pairs, QAPair{
+				Question: fmt.Sprintf("What does %s return?", endpoint),
+				Answer:   returnAnswer(ep, endpoint),
+				Endpoint: endpoint,
+			})
+		}
+	}
+	return pairs
+}
+
+// RenderQAJSONL renders the pairs from RenderQA as JSON Lines, one pair per
+// line.
+func RenderQAJSONL(doc *APIDocument) (string, error) {
+	var sb strings.Builder
+	for _, pair := range RenderQA(doc) {
+		line, err := json.Marshal(pair)
+		if err != nil {
+			return "", err
+		}
+		sb.Write(line)
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// callAnswer explains how to call an endpoint, e.g. "POST /pets with a JSON
+// body containing name and status."
+func callAnswer(ep Endpoint, endpoint string) string {
+	var parts []string
+	byLocation := make(map[string][]string)
+	for _, p := range ep.Parameters {
+		if p != nil && (p.Required || p.In == "path") {
+			byLocation[p.In] = append(byLocation[p.In], p.Name)
+		}
+	}
+	for _, in := range []string{"path", "query", "header", "cookie"} {
+		if names := byLocation[in]; len(names) > 0 {
+			noun := in + " parameter"
+			if len(names) > 1 {
+				noun += "s"
+			}
+			parts = append(parts, fmt.Sprintf("%s %s", noun, joinWords(names)))
+		}
+	}
+
+	if ep.RequestBody != nil {
+		mediaTypes := sortedMediaTypes(ep.RequestBody.Content)
+		if len(mediaTypes) > 0 {
+			mediaType := mediaTypes[0]
+			if _, ok := ep.RequestBody.Content["application/json"]; ok {
+				mediaType = "application/json"
+			}
+			body := fmt.Sprintf("a %s body", mediaType)
+			if mediaType == "application/json" {
+				body = "a JSON body"
+			}
+			if fields := bodyFields(ep.RequestBody.Content[mediaType]); len(fields) > 0 {
+				body += " containing " + joinWords(fields)
+			}
+			parts = append(parts, body)
+		}
+	}
+
+	answer := endpoint
+	if len(parts) > 0 {
+		answer += " with " + joinWords(parts)
+	}
+	return answer + "."
+}
+
+// returnAnswer summarizes an endpoint's success responses, or all of its
+// responses when none are successful.
+func returnAnswer(ep Endpoint, endpoint string) string {
+	var outcomes []string
+	var fallback []string
+	for _, code := range sortedResponseCodes(ep.Responses) {
+		resp := ep.Responses[code]
+		if resp == nil {
+			continue
+		}
+		outcome := code
+		if desc := minifyText(resp.Description); desc != "" {
+			outcome += " (" + desc + ")"
+		}
+		for _, mediaType := range sortedMediaTypes(resp.Content) {
+			if mt := resp.Content[mediaType]; mt != nil && mt.Schema != nil {
+				outcome += fmt.Sprintf(" with %s %s", mediaType, schemaSummary(mt.Schema))
+				break
+			}
+		}
+		if strings.HasPrefix(code, "2") {
+			outcomes = append(outcomes, outcome)
+		} else {
+			fallback = append(fallback, outcome)
+		}
+	}
+	if len(outcomes) == 0 {
+		outcomes = fallback
+	}
+	return fmt.Sprintf("%s responds with %s.", endpoint, joinWords(outcomes))
+}
+
+// bodyFields lists the fields a body must contain: the schema's required
+// properties, or all of its properties when none are marked required.
+func bodyFields(mt *MediaType) []string {
+	if mt == nil || mt.Schema == nil {
+		return nil
+	}
+	if len(mt.Schema.Required) > 0 {
+		return mt.Schema.Required
+	}
+	fields := make([]string, 0, len(mt.Schema.Properties))
+	for name := range mt.Schema.Properties {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// joinWords joins items as an English list: "a", "a and b", "a, b and c".
+func joinWords(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// lowerFirst lowercases the first letter of s unless it starts an acronym,
+// that is, unless the letter after it is not lowercase either.
+func lowerFirst(s string) string {
+	first, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	if next, n := utf8.DecodeRuneInString(s[size:]); n > 0 && !unicode.IsLower(next) {
+		return s
+	}
+	return string(unicode.ToLower(first)) + s[size:]
+}
@@ -0,0 +1,18 @@
+package openapi
+
+import "testing"
+
+func TestLowerFirst(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"", ""},
+		{"List pets", "list pets"},
+		{"API keys", "API keys"},
+		{"Éditer les nœuds", "éditer les nœuds"},
+		{"éditer les nœuds", "éditer les nœuds"},
+		{"ÉTÉ", "ÉTÉ"},
+	} {
+		if got := lowerFirst(tc.in); got != tc.want {
+			t.Errorf("lowerFirst(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// =====================================================
+// Query Serialization
+// =====================================================
+
+// maxExampleArrayItems caps how many enum values an array parameter's
+// synthesized example takes from its item enum.
+const maxExampleArrayItems = 2
+
+// exampleQuery builds a query string for ep from the example, default or
+// first enum value of each query parameter, e.g.
+// "?status=available&limit=20&tags=a&tags=b". Parameters without such a value
+// are left out, and "" is returned when none remain.
+func exampleQuery(ep Endpoint) string {
+	var pairs []string
+	for _, p := range ep.Parameters {
+		if p == nil || p.In != "query" {
+			continue
+		}
+		value, ok := exampleParameterValue(p)
+		if !ok {
+			continue
+		}
+		style, explode := queryStyle(p)
+		pairs = append(pairs, serializeQueryValue(p.Name, toJSONValue(value), style, explode)...)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "?" + strings.Join(pairs, "&")
+}
+
+// exampleParameterValue picks a sample value for p: its example, default or
+// first enum value, looking at the parameter before its schema. Arrays
+// without a value of their own are built from their items.
+func exampleParameterValue(p *Parameter) (interface{}, bool) {
+	if v, ok := firstValue(p.Example, p.Default, p.Enum); ok {
+		return v, true
+	}
+	items := p.Items
+	if s := p.Schema; s != nil {
+		if v, ok := firstValue(s.Example, s.Default, s.Enum); ok {
+			return v, true
+		}
+		if items == nil {
+			items = s.Items
+		}
+	}
+	if items == nil {
+		return nil, false
+	}
+	if len(items.Enum) > 0 {
+		n := len(items.Enum)
+		if n > maxExampleArrayItems {
+			n = maxExampleArrayItems
+		}
+		return items.Enum[:n], true
+	}
+	if v, ok := firstValue(items.Example, items.Default, nil); ok {
+		return []interface{}{v}, true
+	}
+	return nil, false
+}
+
+// firstValue returns example, then def, then the first enum value, whichever
+// is set first.
+func firstValue(example, def interface{}, enum []interface{}) (interface{}, bool) {
+	switch {
+	case example != nil:
+		return example, true
+	case def != nil:
+		return def, true
+	case len(enum) > 0:
+		return enum[0], true
+	}
+	return nil, false
+}
+
+// queryStyle returns the OpenAPI 3 style and explode setting of a query
+// parameter. A Swagger 2.0 collectionFormat is mapped onto the equivalent
+// style when no style is given. Query parameters default to style "form",
+// which explodes by default.
+func queryStyle(p *Parameter) (string, bool) {
+	style := p.Style
+	var explode *bool
+	if style == "" {
+		no, yes := false, true
+		switch p.CollectionFormat {
+		case "csv":
+			style, explode = "form", &no
+		case "ssv":
+			style, explode = "spaceDelimited", &no
+		case "pipes":
+			style, explode = "pipeDelimited", &no
+		case "multi":
+			style, explode = "form", &yes
+		}
+	}
+	if style == "" {
+		style = "form"
+	}
+	if p.Explode != nil {
+		explode = p.Explode
+	}
+	if explode == nil {
+		return style, style == "form"
+	}
+	return style, *explode
+}
+
+// serializeQueryValue renders name=value pairs for one parameter.
+func serializeQueryValue(name string, value interface{}, style string, explode bool) []string {
+	key := url.QueryEscape(name)
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, queryScalar(item))
+		}
+		if explode {
+			pairs := make([]string, 0, len(values))
+			for _, value := range values {
+				pairs = append(pairs, key+"="+value)
+			}
+			return pairs
+		}
+		sep := ","
+		switch style {
+		case "spaceDelimited":
+			sep = "%20"
+		case "pipeDelimited":
+			sep = "|"
+		}
+		return []string{key + "=" + strings.Join(values, sep)}
+	case map[string]interface{}:
+		props := make([]string, 0, len(v))
+		for prop := range v {
+			props = append(props, prop)
+		}
+		sort.Strings(props)
+		var pairs, flat []string
+		for _, prop := range props {
+			propKey, propValue := url.QueryEscape(prop), queryScalar(v[prop])
+			switch {
+			case style == "deepObject":
+				pairs = append(pairs, fmt.Sprintf("%s[%s]=%s", key, propKey, propValue))
+			case explode:
+				pairs = append(pairs, propKey+"="+propValue)
+			default:
+				flat = append(flat, propKey, propValue)
+			}
+		}
+		if flat != nil {
+			return []string{key + "=" + strings.Join(flat, ",")}
+		}
+		return pairs
+	}
+	return []string{key + "=" + queryScalar(value)}
+}
+
+// queryScalar formats a single value for a query string.
+func queryScalar(v interface{}) string {
+	return url.QueryEscape(fmt.Sprintf("%v", v))
+}
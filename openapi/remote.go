@@ -0,0 +1,59 @@
+package openapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// =====================================================
+// Remote Specs
+// =====================================================
+
+// DefaultHTTPTimeout bounds fetching a remote spec when
+// LoadOptions.HTTPTimeout is zero.
+const DefaultHTTPTimeout = 30 * time.Second
+
+// isURL reports whether path names an http:// or https:// resource rather
+// than a local file.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// loadRemoteSpec fetches a spec over HTTP and parses it like a local file,
+// detecting the format from the contents. The Content-Type only settles the
+// one case contents cannot: a body starting with "{" is read as JSON unless
+// it is served as YAML, when it is a YAML flow mapping. A mislabelled body,
+// such as YAML served as application/json, is still detected correctly.
+func loadRemoteSpec(url string, opts LoadOptions) (*APIDocument, error) {
+	timeout := opts.HTTPTimeout
+	if timeout == 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching spec: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching spec %s: unexpected status %s", url, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading spec %s: %w", url, err)
+	}
+	data, err = decompressSpec(data, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && strings.Contains(mediaType, "yaml") {
+		opts.assumeYAML = true
+	}
+	return parseAPISpec(data, opts)
+}
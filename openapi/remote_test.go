@@ -0,0 +1,55 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoadRemoteSpec(t *testing.T) {
+	const jsonSpec = `{"swagger": "2.0", "info": {"title": "Pets", "version": "1"},
+  "paths": {"/pets": {"get": {"responses": {"200": {"description": "ok"}}}}}}`
+	const yamlSpec = `swagger: "2.0"
+info: {title: Pets, version: "1"}
+paths:
+  /pets:
+    get: {responses: {'200': {description: ok}}}
+`
+	const flowSpec = `{swagger: "2.0", info: {title: Pets, version: "1"},
+  paths: {/pets: {get: {responses: {'200': {description: ok}}}}}}`
+
+	bodies := map[string]struct{ contentType, body string }{
+		"/json":         {"application/json", jsonSpec},
+		"/yaml":         {"application/yaml", yamlSpec},
+		"/mislabelled":  {"application/json; charset=utf-8", yamlSpec},
+		"/flow-mapping": {"application/x-yaml", flowSpec},
+		"/unlabelled":   {"text/plain", jsonSpec},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", b.contentType)
+		w.Write([]byte(b.body))
+	}))
+	defer server.Close()
+
+	for path := range bodies {
+		doc, err := LoadAPISpec(server.URL + path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if doc.Title != "Pets" || len(doc.Endpoints) != 1 || doc.Endpoints[0].Path != "/pets" {
+			t.Errorf("%s: got title %q and endpoints %+v, want Pets with GET /pets", path, doc.Title, doc.Endpoints)
+		}
+	}
+
+	_, err := LoadAPISpec(server.URL + "/missing")
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 404") {
+		t.Errorf("got error %v for a missing spec, want unexpected status 404", err)
+	}
+}
@@ -0,0 +1,143 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// =====================================================
+// Security Requirements
+// =====================================================
+
+// securityAnd joins the names of schemes that must be satisfied together in
+// an Endpoint.Security entry.
+const securityAnd = " + "
+
+// operationSecurity returns the Endpoint.Security entries for op and the
+// Endpoint.SecurityScopes parallel to them: its own requirements when it
+// declares any, even an empty list, and otherwise the document-wide ones.
+// Each alternative keeps its own scopes, since satisfying any one of them is
+// enough. Alternatives repeated with the same scopes are listed once.
+func operationSecurity(op Operation, global []SecurityRequirement) ([]string, []map[string][]string) {
+	requirements := global
+	if op.Security != nil {
+		requirements = *op.Security
+		if len(requirements) == 0 {
+			return nil, nil
+		}
+	}
+	var alternatives []string
+	var scopes []map[string][]string
+	hasScopes := false
+	seen := make(map[string]bool)
+	for _, req := range requirements {
+		names := make([]string, 0, len(req))
+		for name := range req {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		alternative := strings.Join(names, securityAnd)
+		key := alternative
+		var reqScopes map[string][]string
+		for _, name := range names {
+			if len(req[name]) == 0 {
+				continue
+			}
+			if reqScopes == nil {
+				reqScopes = make(map[string][]string)
+			}
+			reqScopes[name] = req[name]
+			key += " " + name + ":" + strings.Join(req[name], ",")
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		alternatives = append(alternatives, alternative)
+		scopes = append(scopes, reqScopes)
+		hasScopes = hasScopes || reqScopes != nil
+	}
+	if !hasScopes {
+		scopes = nil
+	}
+	return alternatives, scopes
+}
+
+// securityLine describes what an endpoint needs to authenticate, e.g.
+// "requires apiKey (header: X-API-Key)" or "requires Bearer token (JWT) or
+// HTTP basic auth". scopes, parallel to security, are the OAuth2 scopes each
+// alternative requests per scheme. It returns "none" for an endpoint without
+// requirements.
+func securityLine(security []string, scopes []map[string][]string, schemes map[string]*SecurityScheme) string {
+	if len(security) == 0 {
+		return "none"
+	}
+	alternatives := make([]string, 0, len(security))
+	optional := false
+	for i, alternative := range security {
+		if alternative == "" {
+			optional = true
+			continue
+		}
+		var requested map[string][]string
+		if i < len(scopes) {
+			requested = scopes[i]
+		}
+		names := strings.Split(alternative, securityAnd)
+		parts := make([]string, 0, len(names))
+		for _, name := range names {
+			parts = append(parts, describeSecurityScheme(name, schemes[name], requested[name]))
+		}
+		alternatives = append(alternatives, strings.Join(parts, " and "))
+	}
+	if len(alternatives) == 0 {
+		return "none"
+	}
+	line := "requires " + strings.Join(alternatives, " or ")
+	if optional {
+		line = "optional, " + line
+	}
+	return line
+}
+
+// describeSecurityScheme names the kind of credential a scheme expects,
+// e.g. "OAuth2 (petstore_auth, scopes: read:pets, write:pets)". Scopes are
+// only listed for OAuth2 and OpenID Connect. A scheme missing from the
+// document is described by its name alone.
+func describeSecurityScheme(name string, scheme *SecurityScheme, scopes []string) string {
+	if scheme == nil {
+		return name
+	}
+	switch strings.ToLower(scheme.Type) {
+	case "apikey":
+		return fmt.Sprintf("apiKey (%s: %s)", scheme.In, scheme.Name)
+	case "basic":
+		return "HTTP basic auth"
+	case "http":
+		switch strings.ToLower(scheme.Scheme) {
+		case "bearer":
+			if scheme.BearerFormat != "" {
+				return fmt.Sprintf("Bearer token (%s)", scheme.BearerFormat)
+			}
+			return "Bearer token"
+		case "basic":
+			return "HTTP basic auth"
+		}
+		return fmt.Sprintf("HTTP %s auth", scheme.Scheme)
+	case "oauth2":
+		return fmt.Sprintf("OAuth2 (%s%s)", name, scopeList(scopes))
+	case "openidconnect":
+		return fmt.Sprintf("OpenID Connect (%s%s)", name, scopeList(scopes))
+	}
+	return name
+}
+
+// scopeList formats requested scopes for describeSecurityScheme, e.g.
+// ", scopes: read:pets, write:pets", or "" when there are none.
+func scopeList(scopes []string) string {
+	if len(scopes) == 0 {
+		return ""
+	}
+	return ", scopes: " + strings.Join(scopes, ", ")
+}
@@ -0,0 +1,75 @@
+package openapi
+
+import (
+	"strings"
+	"text/template"
+)
+
+// =====================================================
+// Template Rendering
+// =====================================================
+
+// DefaultTemplate is the RenderText layout written as a template for
+// RenderWithTemplate. It is a starting point for custom layouts, e.g. one
+// that replaces {{endpoint .}} with its own fields.
+const DefaultTemplate = `{{header}}{{range .Endpoints}}{{endpoint .}}{{end}}`
+
+// RenderWithTemplate renders doc with a text/template layout. The template
+// is executed with the *APIDocument as dot and can call these helpers:
+//
+//   - header: the RenderText header, from API through SERVERS
+//   - endpoint: an Endpoint in the RenderText layout, ENDPOINT to END
+//   - schema: a *Schema summarized as in RenderText, e.g. "array[Pet]"
+//   - paramType: the type of a *Parameter, e.g. "integer<int64>"
+//   - security: what an Endpoint needs to authenticate, e.g. "requires ..."
+//   - responseCodes, mediaTypes: sorted keys of responses and content maps
+//   - minify: text with all whitespace collapsed to single spaces
+//   - upper, join: strings.ToUpper and strings.Join
+//
+// An empty tmpl renders DefaultTemplate, the same output as RenderText.
+func RenderWithTemplate(doc *APIDocument, tmpl string) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	t, err := template.New("doc").Funcs(templateFuncs(doc)).Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, doc); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// templateFuncs returns the helpers RenderWithTemplate offers, bound to doc
+// for those that need document-level defaults.
+func templateFuncs(doc *APIDocument) template.FuncMap {
+	r := &textRenderer{doc: doc}
+	return template.FuncMap{
+		"header": func() string {
+			var sb strings.Builder
+			renderHeader(&sb, doc, RenderOptions{})
+			return sb.String()
+		},
+		"endpoint": func(ep Endpoint) string {
+			var sb strings.Builder
+			r.renderEndpoint(&sb, ep, nil)
+			return sb.String()
+		},
+		"schema":    schemaSummary,
+		"paramType": parameterType,
+		"security": func(ep Endpoint) string {
+			var schemes map[string]*SecurityScheme
+			if doc.Components != nil {
+				schemes = doc.Components.SecuritySchemes
+			}
+			return securityLine(ep.Security, ep.SecurityScopes, schemes)
+		},
+		"responseCodes": sortedResponseCodes,
+		"mediaTypes":    sortedMediaTypes,
+		"minify":        minifyText,
+		"upper":         strings.ToUpper,
+		"join":          strings.Join,
+	}
+}